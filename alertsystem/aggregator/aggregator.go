@@ -3,93 +3,207 @@ package aggregator
 
 import (
 	"alertsystem/clickhouse"
+	"alertsystem/config"
+	"alertsystem/enrich"
 	"alertsystem/parser"
 	"alertsystem/rules"
 	"context"
-	"time"
 	"log"
+	"sync"
+	"time"
 )
 
+// Как часто выдавать события входа, не нашедшие пару
+const correlationTick = 1 * time.Second
+
 type Aggregator struct {
-	chClient    *clickhouse.Client
-	bruteRule   *rules.BruteforceRule
-	sprayRule   *rules.PasswordSprayRule
-	sqlInjRule  *rules.SQLInjectionRule
+	sink        Sink
+	rules       *rules.Registry
+	correlator  *correlator
+	clock       *eventClock
+	enricher    *enrich.Enricher
+	opts        Options
 	lastCleanup time.Time
 	ctx         context.Context
+	// writeCtx - контекст записи в получателя: остановка не должна прерывать
+	// запись уже прочитанных записей, иначе они теряются
+	writeCtx context.Context
+	// wg - фоновая выдача событий без пары, завершается после отмены ctx
+	wg sync.WaitGroup
+}
+
+type Options struct {
+	// Replay - повторный анализ архивных логов: ожидание парных записей
+	// при корреляции отсчитывается по времени записей, а не по текущему
+	Replay bool
 }
 
-func New(ctx context.Context, chClient *clickhouse.Client) (*Aggregator, error) {
-	return &Aggregator{
-		chClient:    chClient,
-		bruteRule:   rules.NewBruteforceRule(),
-		sprayRule:   rules.NewPasswordSprayRule(),
-		sqlInjRule:  rules.NewSQLInjectionRule(),
+func New(ctx context.Context, sink Sink, cfg *config.Config, opts Options) (*Aggregator, error) {
+	registry, err := rules.NewRegistry(cfg.Rules)
+	if err != nil {
+		return nil, err
+	}
+
+	enricher, err := enrich.New(enrich.Config{
+		GeoIP:       cfg.GeoIP,
+		ThreatIntel: cfg.ThreatIntel,
+		ReverseDNS:  cfg.ReverseDNS,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Aggregator{
+		sink:        sink,
+		rules:       registry,
+		correlator:  newCorrelator(cfg.Correlation.Tolerance, cfg.Correlation.MaxWait),
+		clock:       newEventClock(cfg.EventTime.AllowedLateness, opts.Replay),
+		enricher:    enricher,
+		opts:        opts,
 		lastCleanup: time.Now(),
 		ctx:         ctx,
-	}, nil
+		writeCtx:    context.WithoutCancel(ctx),
+	}
+	if !opts.Replay {
+		a.wg.Add(1)
+		go a.expireLoop()
+		go enricher.Watch(ctx)
+	}
+	return a, nil
 }
 
+// Close выдает события, ожидающие пару, и закрывает получателя алертов.
+// Вызывается после отмены ctx и остановки источников записей, иначе
+// записи, поступившие после закрытия получателя, теряются.
 func (a *Aggregator) Close() {
-	// Теперь файл не нужен, закрываем ClickHouse клиент
-	if a.chClient != nil {
-		a.chClient.Close()
+	a.wg.Wait()
+
+	for _, event := range a.correlator.flush() {
+		a.processLoginEvent(event)
+	}
+
+	// Теперь файл не нужен, закрываем получателя алертов
+	if a.sink != nil {
+		a.sink.Close()
+	}
+	a.enricher.Close()
+}
+
+// arrival возвращает момент поступления записи для ожидания парной записи
+func (a *Aggregator) arrival(eventTime time.Time) time.Time {
+	if a.opts.Replay {
+		return eventTime
+	}
+	return time.Now()
+}
+
+// expireReplay выдает события без пары, если ожидание отсчитывается по времени записей
+func (a *Aggregator) expireReplay(now time.Time) {
+	if !a.opts.Replay {
+		return
+	}
+	for _, event := range a.correlator.expire(now) {
+		a.processLoginEvent(event)
+	}
+}
+
+// Reload применяет новую конфигурацию, сохраняя текущее состояние правил
+func (a *Aggregator) Reload(cfg *config.Config) error {
+	if err := a.rules.Reload(cfg.Rules); err != nil {
+		return err
 	}
+	a.correlator.configure(cfg.Correlation.Tolerance, cfg.Correlation.MaxWait)
+	a.clock.configure(cfg.EventTime.AllowedLateness)
+	return nil
 }
 
 func (a *Aggregator) ProcessLog(entry parser.LogEntry) {
+	// Записи дополняются до корреляции, и события входа наследуют сведения об адресе
+	entry = a.enricher.Enrich(entry)
+
 	switch log := entry.(type) {
 	case parser.NginxLog:
 		a.processNginxLog(log)
+	case parser.WebServiceLog:
+		a.processWebServiceLog(log)
+	}
+}
+
+// processWebServiceLog проверяет записи приложения, в которых результат
+// аутентификации известен достоверно, а не выводится из кода ответа
+func (a *Aggregator) processWebServiceLog(log parser.WebServiceLog) {
+	now := a.clock.now(log)
+	a.expireReplay(now)
+
+	for _, alert := range a.rules.Check(log, now) {
+		a.writeAlert(alert)
+	}
+
+	if !log.IsLogin() {
+		return
+	}
+
+	for _, event := range a.correlator.addWeb(log, a.arrival(now)) {
+		a.processLoginEvent(event)
 	}
 }
 
 func (a *Aggregator) processNginxLog(log parser.NginxLog) {
-	now := time.Now()
-	
+	now := a.clock.now(log)
+	a.expireReplay(now)
+
 	// Периодическая очистка
 	if now.Sub(a.lastCleanup) > 2*time.Minute {
 		a.lastCleanup = now
 	}
-	
-	// Проверяем только логины
+
+	// Правила для nginx проверяют все запросы, включая сканирование и эксплойты
+	for _, alert := range a.rules.Check(log, now) {
+		a.writeAlert(alert)
+	}
+
+	// С записями приложения сопоставляются только попытки входа
 	if !log.IsLogin() {
 		return
 	}
 
-	authStatus := "failure"
-	if log.Status == "303" {
-		authStatus = "success"
-	}
-	
-	// Проверка правил
-	if alert := a.sqlInjRule.Check(log, now); alert != nil {
-		a.writeAlert(*alert)
-	}
-	
-	if alert := a.bruteRule.Check(log, now); alert != nil {
-		a.writeAlert(*alert)
-	}
-	
-	if alert := a.sprayRule.Check(log, now); alert != nil {
-		a.writeAlert(*alert)
-	}
-	
-	// Запись обычного алерта
-	a.writeAlert(parser.Alert{
-		Type:       "alert_login",
-		Date:       log.TimeLocal,
-		RemoteAddr: log.RemoteAddr,
-		Action:     "login",
-		Username:   log.Username,
-		Password:   log.Password,
-		AuthStatus: authStatus,
-	})
+	for _, event := range a.correlator.addNginx(log, a.arrival(now)) {
+		a.processLoginEvent(event)
+	}
+}
+
+// processLoginEvent записывает объединенное событие входа в login_events
+// и проверяет его правилами; алерты ссылаются на событие по его ID
+func (a *Aggregator) processLoginEvent(event parser.LoginEvent) {
+	now := a.clock.now(event)
+	a.writeLoginEvent(event, now)
+
+	for _, alert := range a.rules.Check(event, now) {
+		a.writeAlert(alert)
+	}
+}
+
+func (a *Aggregator) expireLoop() {
+	defer a.wg.Done()
+	ticker := time.NewTicker(correlationTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, event := range a.correlator.expire(now) {
+				a.processLoginEvent(event)
+			}
+		}
+	}
 }
 
 func (a *Aggregator) writeAlert(alert parser.Alert) {
 	chAlert := clickhouse.Alert{
 		Type:           alert.Type,
+		Severity:       alert.Severity,
 		Date:           alert.Date,
 		RemoteAddr:     alert.RemoteAddr,
 		Action:         alert.Action,
@@ -98,10 +212,74 @@ func (a *Aggregator) writeAlert(alert parser.Alert) {
 		AuthStatus:     alert.AuthStatus,
 		Count:          alert.Count,
 		CommonPassword: alert.CommonPassword,
+		RequestPath:    alert.RequestPath,
+		Details:        alert.Details,
+		Samples:        alert.Samples,
+		EventIDs:       alert.EventIDs,
+		// Адрес алерта может отличаться от адреса записи (например, подсеть),
+		// поэтому сведения о нем определяются отдельно
+		Geo:         toGeo(a.enricher.Geo(alert.RemoteAddr)),
+		ThreatFeeds: a.enricher.ThreatFeeds(alert.RemoteAddr),
+		Hostname:    a.enricher.Hostname(alert.RemoteAddr),
 	}
 
-	if err := a.chClient.InsertAlert(a.ctx, chAlert); err != nil {
+	if err := a.sink.InsertAlert(a.writeCtx, chAlert); err != nil {
 		// Логируем ошибку, но продолжаем работу
-		log.Printf("Failed to insert alert into ClickHouse: %v", err)
+		log.Printf("Failed to write alert: %v", err)
+	}
+}
+
+// eventDate возвращает время самой записи. Время оценки правилами опоздавшей
+// записи сдвинуто к watermark, поэтому оно используется, только если время
+// записи не разбирается
+func eventDate(entry parser.LogEntry, evaluated time.Time) time.Time {
+	t, err := parser.ParseTime(entry.GetTime())
+	if err != nil {
+		return evaluated
+	}
+	return t
+}
+
+func (a *Aggregator) writeLoginEvent(event parser.LoginEvent, now time.Time) {
+	chEvent := clickhouse.LoginEvent{
+		EventID:       event.ID,
+		Date:          eventDate(event, now),
+		RemoteAddr:    event.RemoteAddr,
+		UserAgent:     event.UserAgent,
+		Referer:       event.Referer,
+		Method:        event.Method,
+		RequestPath:   event.RequestPath,
+		Query:         event.Query,
+		Protocol:      event.Protocol,
+		BodyBytesSent: event.BodyBytesSent,
+		RequestTime:   event.RequestTime.Seconds(),
+		ForwardedFor:  event.ForwardedFor,
+		ProxyChain:    event.ProxyChain,
+		Username:      event.Username,
+		Password:      event.Password,
+		AuthStatus:    event.AuthStatus,
+		HTTPStatus:    event.HTTPStatus,
+		Correlated:    event.Correlated,
+		Geo:           toGeo(event.Geo),
+		ThreatFeeds:   event.ThreatFeeds,
+		Hostname:      a.enricher.Hostname(event.RemoteAddr),
+	}
+
+	if err := a.sink.InsertLoginEvent(a.writeCtx, chEvent); err != nil {
+		log.Printf("Failed to write login event: %v", err)
+	}
+}
+
+func toGeo(geo *parser.Geo) clickhouse.Geo {
+	if geo == nil {
+		return clickhouse.Geo{}
 	}
-}
\ No newline at end of file
+	return clickhouse.Geo{
+		Country:   geo.Country,
+		City:      geo.City,
+		Latitude:  geo.Latitude,
+		Longitude: geo.Longitude,
+		ASN:       geo.ASN,
+		ASOrg:     geo.Org,
+	}
+}
@@ -0,0 +1,63 @@
+package aggregator
+
+import (
+	"alertsystem/parser"
+	"log"
+	"sync"
+	"time"
+)
+
+// eventClock выдает время, по которому правила оценивают записи: время самой
+// записи, а не момент ее чтения. Иначе после простоя наблюдателя накопившиеся
+// попытки попадают в одно окно правила. Записи, опоздавшие относительно
+// самой поздней из виденных больше чем на lateness (watermark), оцениваются
+// по watermark, чтобы окна правил не откатывались назад.
+type eventClock struct {
+	mu       sync.Mutex
+	lateness time.Duration
+	// replay - ingestion time бессмысленно для архивных логов,
+	// при ошибке разбора используется время предыдущей записи
+	replay  bool
+	maxSeen time.Time
+}
+
+func newEventClock(lateness time.Duration, replay bool) *eventClock {
+	return &eventClock{
+		lateness: lateness,
+		replay:   replay,
+	}
+}
+
+func (c *eventClock) configure(lateness time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lateness = lateness
+}
+
+func (c *eventClock) now(entry parser.LogEntry) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t, err := parser.ParseTime(entry.GetTime())
+	if err != nil {
+		if c.replay && !c.maxSeen.IsZero() {
+			log.Printf("Failed to parse event time %q, using previous event time: %v", entry.GetTime(), err)
+			return c.maxSeen
+		}
+		log.Printf("Failed to parse event time %q, using ingestion time: %v", entry.GetTime(), err)
+		t = time.Now()
+	}
+
+	if t.After(c.maxSeen) {
+		c.maxSeen = t
+		return t
+	}
+
+	watermark := c.maxSeen.Add(-c.lateness)
+	if t.Before(watermark) {
+		log.Printf("Late %s event at %s is behind watermark %s, evaluating at watermark",
+			entry.Source(), t.Format(time.RFC3339), watermark.Format(time.RFC3339))
+		return watermark
+	}
+	return t
+}
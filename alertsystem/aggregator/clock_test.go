@@ -0,0 +1,83 @@
+package aggregator
+
+import (
+	"alertsystem/parser"
+	"testing"
+	"time"
+)
+
+func TestEventClock(t *testing.T) {
+	at := func(s string) parser.NginxLog {
+		return parser.NginxLog{TimeLocal: s}
+	}
+	utc := func(s string) time.Time {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			panic(err)
+		}
+		return t
+	}
+
+	clock := newEventClock(30*time.Second, false)
+	tests := []struct {
+		name  string
+		entry parser.NginxLog
+		want  time.Time
+	}{
+		{"first event", at("18/Oct/2026:10:00:00 +0300"), utc("2026-10-18T07:00:00Z")},
+		{"later event moves watermark", at("18/Oct/2026:10:01:00 +0300"), utc("2026-10-18T07:01:00Z")},
+		{"late within lateness", at("18/Oct/2026:10:00:40 +0300"), utc("2026-10-18T07:00:40Z")},
+		{"exactly at watermark", at("18/Oct/2026:10:00:30 +0300"), utc("2026-10-18T07:00:30Z")},
+		{"behind watermark", at("18/Oct/2026:10:00:10 +0300"), utc("2026-10-18T07:00:30Z")},
+		{"other offset, same instant", at("18/Oct/2026:07:01:00 +0000"), utc("2026-10-18T07:01:00Z")},
+		{"event after watermark advanced", at("18/Oct/2026:10:02:00 +0300"), utc("2026-10-18T07:02:00Z")},
+		{"old event again", at("18/Oct/2026:10:00:40 +0300"), utc("2026-10-18T07:01:30Z")},
+	}
+	for _, tt := range tests {
+		if got := clock.now(tt.entry); !got.Equal(tt.want) {
+			t.Errorf("%s: now() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	// Новое допустимое отставание применяется к следующим записям
+	clock.configure(2 * time.Minute)
+	if got, want := clock.now(at("18/Oct/2026:10:00:40 +0300")), utc("2026-10-18T07:00:40Z"); !got.Equal(want) {
+		t.Errorf("after configure: now() = %s, want %s", got, want)
+	}
+}
+
+func TestEventClockUnparsableTime(t *testing.T) {
+	entry := parser.NginxLog{TimeLocal: "yesterday"}
+
+	replay := newEventClock(30*time.Second, true)
+	last := replay.now(parser.NginxLog{TimeLocal: "18/Oct/2026:10:00:00 +0300"})
+	if got := replay.now(entry); !got.Equal(last) {
+		t.Errorf("replay: now() = %s, want previous event time %s", got, last)
+	}
+
+	live := newEventClock(30*time.Second, false)
+	before := time.Now()
+	if got := live.now(entry); got.Before(before) || got.After(time.Now()) {
+		t.Errorf("live: now() = %s, want ingestion time", got)
+	}
+}
+
+func TestEventDateIgnoresWatermark(t *testing.T) {
+	clock := newEventClock(30*time.Second, false)
+	clock.now(parser.LoginEvent{TimeLocal: "18/Oct/2026:10:05:00 +0300"})
+
+	late := parser.LoginEvent{TimeLocal: "18/Oct/2026:10:00:00 +0300"}
+	evaluated := clock.now(late)
+	if want := time.Date(2026, 10, 18, 7, 4, 30, 0, time.UTC); !evaluated.Equal(want) {
+		t.Fatalf("evaluated at %s, want watermark %s", evaluated, want)
+	}
+	// В login_events сохраняется время самой попытки, а не watermark
+	if got, want := eventDate(late, evaluated), time.Date(2026, 10, 18, 7, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("eventDate() = %s, want %s", got, want)
+	}
+
+	bad := parser.LoginEvent{TimeLocal: "yesterday"}
+	if got := eventDate(bad, evaluated); !got.Equal(evaluated) {
+		t.Errorf("unparsable time: eventDate() = %s, want %s", got, evaluated)
+	}
+}
@@ -0,0 +1,167 @@
+package aggregator
+
+import (
+	"alertsystem/parser"
+	"sync"
+	"time"
+)
+
+type pendingNginx struct {
+	log       parser.NginxLog
+	eventTime time.Time
+	arrived   time.Time
+}
+
+type pendingWeb struct {
+	log       parser.WebServiceLog
+	eventTime time.Time
+	arrived   time.Time
+}
+
+// correlator сопоставляет попытки входа из nginx и веб-приложения по имени
+// пользователя и времени. Записи без пары ждут maxWait (логи двух источников
+// сбрасываются на диск с разной задержкой), после чего выдаются по отдельности.
+type correlator struct {
+	mu        sync.Mutex
+	tolerance time.Duration
+	maxWait   time.Duration
+	nginx     []pendingNginx
+	web       []pendingWeb
+}
+
+func newCorrelator(tolerance, maxWait time.Duration) *correlator {
+	return &correlator{
+		tolerance: tolerance,
+		maxWait:   maxWait,
+	}
+}
+
+func (c *correlator) configure(tolerance, maxWait time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tolerance = tolerance
+	c.maxWait = maxWait
+}
+
+func (c *correlator) addNginx(log parser.NginxLog, arrived time.Time) []parser.LoginEvent {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	eventTime, err := parser.ParseTime(log.TimeLocal)
+	if err != nil {
+		// Без времени сопоставить запись невозможно
+		return []parser.LoginEvent{parser.LoginEventFromNginx(log)}
+	}
+
+	best := -1
+	for i, p := range c.web {
+		if !c.matches(log, p.log, eventTime, p.eventTime) {
+			continue
+		}
+		if best == -1 || absDuration(eventTime.Sub(p.eventTime)) < absDuration(eventTime.Sub(c.web[best].eventTime)) {
+			best = i
+		}
+	}
+	if best >= 0 {
+		web := c.web[best].log
+		c.web = append(c.web[:best], c.web[best+1:]...)
+		return []parser.LoginEvent{parser.NewLoginEvent(log, web)}
+	}
+
+	c.nginx = append(c.nginx, pendingNginx{log: log, eventTime: eventTime, arrived: arrived})
+	return nil
+}
+
+func (c *correlator) addWeb(log parser.WebServiceLog, arrived time.Time) []parser.LoginEvent {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	eventTime, err := parser.ParseTime(log.TimeLocal)
+	if err != nil {
+		return []parser.LoginEvent{parser.LoginEventFromWeb(log)}
+	}
+
+	best := -1
+	for i, p := range c.nginx {
+		if !c.matches(p.log, log, p.eventTime, eventTime) {
+			continue
+		}
+		if best == -1 || absDuration(eventTime.Sub(p.eventTime)) < absDuration(eventTime.Sub(c.nginx[best].eventTime)) {
+			best = i
+		}
+	}
+	if best >= 0 {
+		nginx := c.nginx[best].log
+		c.nginx = append(c.nginx[:best], c.nginx[best+1:]...)
+		return []parser.LoginEvent{parser.NewLoginEvent(nginx, log)}
+	}
+
+	c.web = append(c.web, pendingWeb{log: log, eventTime: eventTime, arrived: arrived})
+	return nil
+}
+
+func (c *correlator) matches(nginx parser.NginxLog, web parser.WebServiceLog, nginxTime, webTime time.Time) bool {
+	if nginx.Username != web.Username {
+		return false
+	}
+	// Если приложение записало адрес клиента, он должен совпадать. Приложение
+	// не видит X-Real-IP от прокси перед nginx и может знать только адрес прокси.
+	if web.RemoteAddr != "" && nginx.RemoteAddr != web.RemoteAddr && !nginx.ViaProxy(web.RemoteAddr) {
+		return false
+	}
+	return absDuration(nginxTime.Sub(webTime)) <= c.tolerance
+}
+
+// expire возвращает события для записей, не нашедших пару за maxWait
+func (c *correlator) expire(now time.Time) []parser.LoginEvent {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var events []parser.LoginEvent
+
+	nginx := c.nginx[:0]
+	for _, p := range c.nginx {
+		if now.Sub(p.arrived) >= c.maxWait {
+			events = append(events, parser.LoginEventFromNginx(p.log))
+		} else {
+			nginx = append(nginx, p)
+		}
+	}
+	c.nginx = nginx
+
+	web := c.web[:0]
+	for _, p := range c.web {
+		if now.Sub(p.arrived) >= c.maxWait {
+			events = append(events, parser.LoginEventFromWeb(p.log))
+		} else {
+			web = append(web, p)
+		}
+	}
+	c.web = web
+
+	return events
+}
+
+// flush выдает все ожидающие записи, используется при остановке
+func (c *correlator) flush() []parser.LoginEvent {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var events []parser.LoginEvent
+	for _, p := range c.nginx {
+		events = append(events, parser.LoginEventFromNginx(p.log))
+	}
+	for _, p := range c.web {
+		events = append(events, parser.LoginEventFromWeb(p.log))
+	}
+	c.nginx = nil
+	c.web = nil
+	return events
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,132 @@
+package aggregator
+
+import (
+	"alertsystem/parser"
+	"testing"
+	"time"
+)
+
+func nginxLogin(timeLocal, addr, username string) parser.NginxLog {
+	return parser.NginxLog{
+		TimeLocal:  timeLocal,
+		RemoteAddr: addr,
+		Status:     "200",
+		Method:     "POST",
+		Path:       "/login",
+		Username:   username,
+	}
+}
+
+func webLogin(timeLocal, addr, username, status string) parser.WebServiceLog {
+	return parser.WebServiceLog{
+		TimeLocal:  timeLocal,
+		RemoteAddr: addr,
+		Username:   username,
+		Status:     status,
+	}
+}
+
+func TestCorrelatorMatches(t *testing.T) {
+	const at = "18/Oct/2026:10:00:00 +0300"
+	proxied := nginxLogin(at, "198.51.100.1", "admin")
+	proxied.ProxyChain = []string{"10.0.0.1"}
+
+	tests := []struct {
+		name  string
+		nginx parser.NginxLog
+		web   parser.WebServiceLog
+		match bool
+	}{
+		{"same user and address", nginxLogin(at, "192.0.2.1", "admin"), webLogin(at, "192.0.2.1", "admin", parser.AuthSuccess), true},
+		{"within tolerance", nginxLogin(at, "192.0.2.1", "admin"), webLogin("18/Oct/2026:10:00:02 +0300", "192.0.2.1", "admin", parser.AuthSuccess), true},
+		{"outside tolerance", nginxLogin(at, "192.0.2.1", "admin"), webLogin("18/Oct/2026:10:00:03 +0300", "192.0.2.1", "admin", parser.AuthSuccess), false},
+		{"same instant, other offset", nginxLogin(at, "192.0.2.1", "admin"), webLogin("18/Oct/2026:07:00:00 +0000", "192.0.2.1", "admin", parser.AuthSuccess), true},
+		{"other user", nginxLogin(at, "192.0.2.1", "admin"), webLogin(at, "192.0.2.1", "root", parser.AuthSuccess), false},
+		{"other address", nginxLogin(at, "192.0.2.1", "admin"), webLogin(at, "192.0.2.2", "admin", parser.AuthSuccess), false},
+		{"web without address", nginxLogin(at, "192.0.2.1", "admin"), webLogin(at, "", "admin", parser.AuthSuccess), true},
+		{"web knows only the proxy", proxied, webLogin(at, "10.0.0.1", "admin", parser.AuthSuccess), true},
+		{"web resolved the client too", proxied, webLogin(at, "198.51.100.1", "admin", parser.AuthSuccess), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCorrelator(2*time.Second, 10*time.Second)
+			now := time.Now()
+			if events := c.addNginx(tt.nginx, now); len(events) != 0 {
+				t.Fatalf("addNginx emitted %d events before a pair arrived", len(events))
+			}
+			events := c.addWeb(tt.web, now)
+			if !tt.match {
+				if len(events) != 0 {
+					t.Fatalf("addWeb emitted %v, want no match", events)
+				}
+				return
+			}
+			if len(events) != 1 {
+				t.Fatalf("addWeb emitted %d events, want 1", len(events))
+			}
+			event := events[0]
+			if !event.Correlated || event.RemoteAddr != tt.nginx.RemoteAddr || event.AuthStatus != tt.web.Status {
+				t.Errorf("event = %+v", event)
+			}
+		})
+	}
+}
+
+func TestCorrelatorPicksClosest(t *testing.T) {
+	c := newCorrelator(2*time.Second, 10*time.Second)
+	now := time.Now()
+	c.addWeb(webLogin("18/Oct/2026:10:00:00 +0300", "192.0.2.1", "admin", parser.AuthFailure), now)
+	c.addWeb(webLogin("18/Oct/2026:10:00:02 +0300", "192.0.2.1", "admin", parser.AuthSuccess), now)
+
+	events := c.addNginx(nginxLogin("18/Oct/2026:10:00:02 +0300", "192.0.2.1", "admin"), now)
+	if len(events) != 1 || events[0].AuthStatus != parser.AuthSuccess {
+		t.Fatalf("events = %+v, want the success record 0s away", events)
+	}
+	if len(c.web) != 1 || c.web[0].log.Status != parser.AuthFailure {
+		t.Errorf("pending web records = %+v, want the failure record", c.web)
+	}
+}
+
+func TestCorrelatorExpire(t *testing.T) {
+	c := newCorrelator(2*time.Second, 10*time.Second)
+	start := time.Date(2026, 10, 18, 7, 0, 0, 0, time.UTC)
+	c.addNginx(nginxLogin("18/Oct/2026:10:00:00 +0300", "192.0.2.1", "admin"), start)
+	c.addWeb(webLogin("18/Oct/2026:10:00:05 +0300", "192.0.2.2", "root", parser.AuthFailure), start.Add(5*time.Second))
+
+	tests := []struct {
+		now     time.Time
+		sources []string
+	}{
+		{start.Add(9 * time.Second), nil},
+		{start.Add(10 * time.Second), []string{"nginx"}},
+		{start.Add(14 * time.Second), nil},
+		{start.Add(15 * time.Second), []string{"web"}},
+	}
+	for _, tt := range tests {
+		events := c.expire(tt.now)
+		if len(events) != len(tt.sources) {
+			t.Fatalf("expire(+%s) = %d events, want %d", tt.now.Sub(start), len(events), len(tt.sources))
+		}
+		for i, event := range events {
+			// Событие без пары: из nginx статус выводится по коду ответа
+			fromNginx := event.HTTPStatus != ""
+			if event.Correlated || fromNginx != (tt.sources[i] == "nginx") {
+				t.Errorf("expire(+%s) event %d = %+v, want uncorrelated %s event", tt.now.Sub(start), i, event, tt.sources[i])
+			}
+		}
+	}
+}
+
+func TestCorrelatorFlush(t *testing.T) {
+	c := newCorrelator(2*time.Second, 10*time.Second)
+	now := time.Now()
+	c.addNginx(nginxLogin("18/Oct/2026:10:00:00 +0300", "192.0.2.1", "admin"), now)
+	c.addWeb(webLogin("18/Oct/2026:10:00:00 +0300", "192.0.2.2", "root", parser.AuthFailure), now)
+
+	if events := c.flush(); len(events) != 2 {
+		t.Errorf("flush() = %d events, want 2", len(events))
+	}
+	if events := c.flush(); len(events) != 0 {
+		t.Errorf("second flush() = %d events, want 0", len(events))
+	}
+}
@@ -0,0 +1,41 @@
+package aggregator
+
+import (
+	"alertsystem/clickhouse"
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// Sink - получатель алертов и событий входа. Основная реализация - clickhouse.Writer.
+type Sink interface {
+	InsertAlert(ctx context.Context, alert clickhouse.Alert) error
+	InsertLoginEvent(ctx context.Context, event clickhouse.LoginEvent) error
+	Close() error
+}
+
+// JSONSink пишет алерты построчно в JSON, например в stdout при повторном анализе логов.
+// События входа не выводятся: результат повторного анализа - алерты.
+type JSONSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{enc: json.NewEncoder(w)}
+}
+
+func (s *JSONSink) InsertAlert(ctx context.Context, alert clickhouse.Alert) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enc.Encode(alert)
+}
+
+func (s *JSONSink) InsertLoginEvent(ctx context.Context, event clickhouse.LoginEvent) error {
+	return nil
+}
+
+func (s *JSONSink) Close() error {
+	return nil
+}
@@ -1,9 +1,11 @@
 package clickhouse
 
 import (
+	"chconfig"
 	"context"
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
 	"github.com/ClickHouse/clickhouse-go/v2"
@@ -12,54 +14,62 @@ import (
 
 type Client struct {
 	conn driver.Conn
+	// ready - соединение проверено и таблицы созданы
+	mu    sync.Mutex
+	ready bool
 }
 
-func New(ctx context.Context) (*Client, error) {
-	conn, err := clickhouse.Open(&clickhouse.Options{
-		Addr: []string{"clickhouse:9000"},
-		Auth: clickhouse.Auth{
-			Database: "alerts_db",
-			Username: "alerts_user",
-			Password: "alerts_password",
-		},
-		DialTimeout: 5 * time.Second,
-	})
+// New подключается к ClickHouse и готовит таблицы, возвращая ошибку, если сервер недоступен
+func New(ctx context.Context, cfg chconfig.Config) (*Client, error) {
+	c, err := Open(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to ClickHouse: %w", err)
+		return nil, err
 	}
-
-	if err := conn.Ping(ctx); err != nil {
-		return nil, fmt.Errorf("failed to ping ClickHouse: %w", err)
+	if err := c.Init(ctx); err != nil {
+		c.Close()
+		return nil, err
 	}
+	return c, nil
+}
 
-	// Создаем таблицу, если она не существует
-	if err := createTables(ctx, conn); err != nil {
-		return nil, fmt.Errorf("failed to create tables: %w", err)
+// Open создает клиент без обращения к серверу. Подключение и создание таблиц
+// выполняются при первой вставке, поэтому сервер может быть недоступен при запуске.
+func Open(cfg chconfig.Config) (*Client, error) {
+	opts, err := cfg.Options()
+	if err != nil {
+		return nil, err
+	}
+	if opts.DialTimeout == 0 {
+		opts.DialTimeout = 5 * time.Second
 	}
 
+	conn, err := clickhouse.Open(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to ClickHouse: %w", err)
+	}
+	log.Printf("Using ClickHouse %s", chconfig.Describe(opts))
 	return &Client{conn: conn}, nil
 }
 
-func createTables(ctx context.Context, conn driver.Conn) error {
-	// Таблица для хранения всех алертов
-	if err := conn.Exec(ctx, `
-		CREATE TABLE IF NOT EXISTS alerts (
-			type String,
-			date DateTime,
-			remote_addr String,
-			action String,
-			username Nullable(String),
-			password Nullable(String),
-			auth_status Nullable(String),
-			count Nullable(UInt32),
-			common_password Nullable(String)
-		) ENGINE = MergeTree()
-		ORDER BY (date, type)
-	`); err != nil {
-		return fmt.Errorf("failed to create alerts table: %w", err)
+// Init проверяет соединение и применяет миграции схемы, если это еще не сделано
+func (c *Client) Init(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ready {
+		return nil
+	}
+
+	if err := c.conn.Ping(ctx); err != nil {
+		return fmt.Errorf("failed to ping ClickHouse: %w", err)
 	}
 
-	// Можно создать дополнительные таблицы для каждого типа алертов, если нужно
+	// Приводим схему к текущей версии
+	if _, err := c.Migrate(ctx); err != nil {
+		return fmt.Errorf("failed to migrate schema: %w", err)
+	}
+
+	c.ready = true
 	return nil
 }
 
@@ -68,11 +78,21 @@ func (c *Client) Close() error {
 }
 
 func (c *Client) InsertAlert(ctx context.Context, alert Alert) error {
+	return c.InsertAlerts(ctx, []Alert{alert})
+}
+
+// InsertAlerts отправляет алерты одним батчем
+func (c *Client) InsertAlerts(ctx context.Context, alerts []Alert) error {
+	if err := c.Init(ctx); err != nil {
+		return err
+	}
+
 	query := `
 		INSERT INTO alerts (
-			type, date, remote_addr, action, username, password, 
-			auth_status, count, common_password
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			type, severity, date, remote_addr, action, username, password,
+			auth_status, count, common_password, request_path, details, samples, event_ids,
+			country, city, latitude, longitude, asn, as_org, threat_feeds, hostname
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	batch, err := c.conn.PrepareBatch(ctx, query)
@@ -80,31 +100,92 @@ func (c *Client) InsertAlert(ctx context.Context, alert Alert) error {
 		return fmt.Errorf("failed to prepare batch: %w", err)
 	}
 
-	if err := batch.Append(
-		alert.Type,
-		parseTime(alert.Date),
-		alert.RemoteAddr,
-		alert.Action,
-		alert.Username,
-		alert.Password,
-		alert.AuthStatus,
-		alert.Count,
-		alert.CommonPassword,
-	); err != nil {
-		return fmt.Errorf("failed to append alert to batch: %w", err)
+	for _, alert := range alerts {
+		if err := batch.Append(
+			alert.Type,
+			alert.Severity,
+			alert.Date,
+			alert.RemoteAddr,
+			alert.Action,
+			alert.Username,
+			alert.Password,
+			alert.AuthStatus,
+			alert.Count,
+			alert.CommonPassword,
+			alert.RequestPath,
+			alert.Details,
+			alert.Samples,
+			alert.EventIDs,
+			alert.Country,
+			alert.City,
+			alert.Latitude,
+			alert.Longitude,
+			alert.ASN,
+			alert.ASOrg,
+			alert.ThreatFeeds,
+			alert.Hostname,
+		); err != nil {
+			batch.Abort()
+			return permanent(fmt.Errorf("failed to append alert to batch: %w", err))
+		}
 	}
 
 	return batch.Send()
 }
 
-// parseTime преобразует строку времени из логов в time.Time
-func parseTime(timeStr string) time.Time {
-	// Формат времени должен соответствовать тому, что приходит в логах
-	// Пример: "02/Jan/2006:15:04:05 -0700"
-	t, err := time.Parse("02/Jan/2006:15:04:05", timeStr)
+// InsertLoginEvents отправляет события входа одним батчем
+func (c *Client) InsertLoginEvents(ctx context.Context, events []LoginEvent) error {
+	if err := c.Init(ctx); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO login_events (
+			event_id, date, remote_addr, user_agent, referer, method, request_path,
+			query, protocol, body_bytes_sent, request_time, x_forwarded_for,
+			proxy_chain, username, password, auth_status, http_status, correlated,
+			country, city, latitude, longitude, asn, as_org, threat_feeds, hostname
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	batch, err := c.conn.PrepareBatch(ctx, query)
 	if err != nil {
-		log.Printf("Failed to parse time %q: %v", timeStr, err)
-		return time.Now()
+		return fmt.Errorf("failed to prepare batch: %w", err)
+	}
+
+	for _, event := range events {
+		if err := batch.Append(
+			event.EventID,
+			event.Date,
+			event.RemoteAddr,
+			event.UserAgent,
+			event.Referer,
+			event.Method,
+			event.RequestPath,
+			event.Query,
+			event.Protocol,
+			uint64(max(event.BodyBytesSent, 0)),
+			event.RequestTime,
+			event.ForwardedFor,
+			event.ProxyChain,
+			event.Username,
+			event.Password,
+			event.AuthStatus,
+			event.HTTPStatus,
+			event.Correlated,
+			event.Country,
+			event.City,
+			event.Latitude,
+			event.Longitude,
+			event.ASN,
+			event.ASOrg,
+			event.ThreatFeeds,
+			event.Hostname,
+		); err != nil {
+			batch.Abort()
+			return permanent(fmt.Errorf("failed to append login event to batch: %w", err))
+		}
 	}
-	return t
-}
\ No newline at end of file
+
+	return batch.Send()
+}
@@ -0,0 +1,213 @@
+package clickhouse
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+)
+
+// Миграции схемы: файлы NNNN_описание.sql, применяются по возрастанию номера.
+// Операторы в файле разделяются точкой с запятой в конце строки.
+// Применённая миграция не меняется - для изменения схемы добавляется новая.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+const createMigrationsTable = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version UInt32,
+		name String,
+		applied_at DateTime64(3, 'UTC')
+	) ENGINE = MergeTree()
+	ORDER BY version
+`
+
+type Migration struct {
+	Version    int
+	Name       string
+	Statements []string
+}
+
+// MigrationStatus - миграция и время ее применения (нулевое, если не применена)
+type MigrationStatus struct {
+	Migration
+	AppliedAt time.Time
+}
+
+// Migrations возвращает встроенные миграции по возрастанию версии
+func Migrations() ([]Migration, error) {
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	var migrations []Migration
+	seen := make(map[int]string)
+	for _, entry := range entries {
+		file := entry.Name()
+		prefix, name, ok := strings.Cut(strings.TrimSuffix(file, ".sql"), "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("bad migration file name %q, expected NNNN_name.sql", file)
+		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migrations %s and %s have the same version", other, file)
+		}
+		seen[version] = file
+
+		data, err := migrationFiles.ReadFile(path.Join("migrations", file))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", file, err)
+		}
+		migrations = append(migrations, Migration{
+			Version:    version,
+			Name:       name,
+			Statements: splitStatements(string(data)),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// splitStatements делит файл на операторы по точке с запятой в конце строки
+func splitStatements(sql string) []string {
+	var statements []string
+	var current strings.Builder
+	for _, line := range strings.Split(sql, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = appendStatement(statements, current.String())
+			current.Reset()
+		}
+	}
+	return appendStatement(statements, current.String())
+}
+
+func appendStatement(statements []string, statement string) []string {
+	statement = strings.TrimSuffix(strings.TrimSpace(statement), ";")
+	if statement == "" {
+		return statements
+	}
+	return append(statements, statement)
+}
+
+// MigrationStatuses возвращает все миграции с отметкой о применении
+func (c *Client) MigrationStatuses(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(ctx, c.conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		statuses = append(statuses, MigrationStatus{
+			Migration: migration,
+			AppliedAt: applied[migration.Version],
+		})
+	}
+	return statuses, nil
+}
+
+// Migrate применяет недостающие миграции по порядку и возвращает примененные
+func (c *Client) Migrate(ctx context.Context) ([]Migration, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(ctx, c.conn)
+	if err != nil {
+		return nil, err
+	}
+
+	vars, err := migrationVars()
+	if err != nil {
+		return nil, err
+	}
+	// ALTER ... DELETE/UPDATE по умолчанию выполняются в фоне. Миграция
+	// отмечается примененной только после завершения изменений на всех репликах.
+	execCtx := clickhouse.Context(ctx, clickhouse.WithSettings(clickhouse.Settings{
+		"mutations_sync": 2,
+	}))
+
+	var done []Migration
+	for _, migration := range migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		log.Printf("Applying migration %04d_%s", migration.Version, migration.Name)
+		for _, statement := range migration.Statements {
+			if err := c.conn.Exec(execCtx, vars.Replace(statement)); err != nil {
+				return done, fmt.Errorf("failed to apply migration %04d_%s: %w", migration.Version, migration.Name, err)
+			}
+		}
+
+		if err := c.conn.Exec(ctx,
+			`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+			uint32(migration.Version), migration.Name, time.Now().UTC(),
+		); err != nil {
+			return done, fmt.Errorf("failed to record migration %04d_%s: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+func appliedMigrations(ctx context.Context, conn driver.Conn) (map[int]time.Time, error) {
+	if err := conn.Exec(ctx, createMigrationsTable); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	rows, err := conn.Query(ctx, `SELECT version, min(applied_at) FROM schema_migrations GROUP BY version`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version uint32
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan applied migration: %w", err)
+		}
+		applied[int(version)] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// migrationVars - подстановки в тексте миграций.
+// {{log_timezone}} - часовой пояс логов (TZ), в котором записывалось время
+// до перехода на DateTime64 в UTC.
+func migrationVars() (*strings.Replacer, error) {
+	logTZ := os.Getenv("TZ")
+	if logTZ == "" {
+		logTZ = "UTC"
+	}
+	if _, err := time.LoadLocation(logTZ); err != nil {
+		return nil, fmt.Errorf("invalid TZ %q: %w", logTZ, err)
+	}
+	return strings.NewReplacer("{{log_timezone}}", logTZ), nil
+}
@@ -0,0 +1,53 @@
+package clickhouse
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMigrationsOrder(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no embedded migrations")
+	}
+	// Версии идут подряд с 1: пропуск означает потерянный или переименованный файл
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			t.Errorf("migration %d has version %d (%s), want %d", i, migration.Version, migration.Name, i+1)
+		}
+		if len(migration.Statements) == 0 {
+			t.Errorf("migration %04d_%s has no statements", migration.Version, migration.Name)
+		}
+		for _, statement := range migration.Statements {
+			if strings.HasSuffix(statement, ";") {
+				t.Errorf("migration %04d_%s: statement keeps the semicolon: %q", migration.Version, migration.Name, statement)
+			}
+		}
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want []string
+	}{
+		{"single", "CREATE TABLE t (x UInt8);", []string{"CREATE TABLE t (x UInt8)"}},
+		{"without final semicolon", "SELECT 1", []string{"SELECT 1"}},
+		{
+			"multiline with comments",
+			"-- комментарий\nALTER TABLE t\n    ADD COLUMN a String;\n\n-- еще один; с точкой с запятой\nALTER TABLE t DELETE WHERE a = 'x;y';\n",
+			[]string{"ALTER TABLE t\n    ADD COLUMN a String", "ALTER TABLE t DELETE WHERE a = 'x;y'"},
+		},
+		{"only comments", "-- ничего\n\n", nil},
+	}
+	for _, tt := range tests {
+		if got := splitStatements(tt.sql); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: splitStatements() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
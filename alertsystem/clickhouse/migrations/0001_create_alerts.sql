@@ -0,0 +1,13 @@
+-- Исходная схема таблицы алертов
+CREATE TABLE IF NOT EXISTS alerts (
+    type String,
+    date DateTime,
+    remote_addr String,
+    action String,
+    username Nullable(String),
+    password Nullable(String),
+    auth_status Nullable(String),
+    count Nullable(UInt32),
+    common_password Nullable(String)
+) ENGINE = MergeTree()
+ORDER BY (date, type);
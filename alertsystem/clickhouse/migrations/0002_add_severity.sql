@@ -0,0 +1,2 @@
+-- Уровень критичности алерта
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS severity LowCardinality(String) DEFAULT '' AFTER type;
@@ -0,0 +1,35 @@
+-- Время в UTC с миллисекундами, партиции по месяцам и срок хранения год.
+-- Ключ, партиции и тип ключевого столбца нельзя изменить через ALTER,
+-- поэтому данные копируются в новую таблицу, которая затем подменяет старую.
+-- Значения date типа DateTime записывались как локальное время логов без смещения
+-- и пересчитываются из часового пояса логов; DateTime64 уже хранится в UTC.
+DROP TABLE IF EXISTS alerts_0003;
+
+CREATE TABLE alerts_0003 (
+    type String,
+    severity LowCardinality(String) DEFAULT '',
+    date DateTime64(3, 'UTC'),
+    remote_addr String,
+    action String,
+    username Nullable(String),
+    password Nullable(String),
+    auth_status Nullable(String),
+    count Nullable(UInt32),
+    common_password Nullable(String)
+) ENGINE = MergeTree()
+PARTITION BY toYYYYMM(date)
+ORDER BY (date, type)
+TTL toDateTime(date) + INTERVAL 1 YEAR;
+
+INSERT INTO alerts_0003 (type, severity, date, remote_addr, action, username, password,
+    auth_status, count, common_password)
+SELECT type, severity,
+    if(toTypeName(date) = 'DateTime',
+        toTimeZone(toDateTime64(toString(date), 3, '{{log_timezone}}'), 'UTC'),
+        toTimeZone(toDateTime64(date, 3), 'UTC')),
+    remote_addr, action, username, password, auth_status, count, common_password
+FROM alerts;
+
+EXCHANGE TABLES alerts AND alerts_0003;
+
+DROP TABLE alerts_0003;
@@ -0,0 +1,30 @@
+-- Попытки входа хранятся отдельно от алертов, алерты ссылаются на них по event_id
+CREATE TABLE IF NOT EXISTS login_events (
+    event_id UUID,
+    date DateTime64(3, 'UTC'),
+    remote_addr String,
+    user_agent String,
+    referer String,
+    request_path String,
+    body_bytes_sent UInt64,
+    username String,
+    password String,
+    auth_status LowCardinality(String),
+    http_status LowCardinality(String),
+    correlated Bool
+) ENGINE = MergeTree()
+PARTITION BY toYYYYMM(date)
+ORDER BY (date, remote_addr)
+TTL toDateTime(date) + INTERVAL 1 YEAR;
+
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS event_ids Array(UUID) AFTER common_password;
+
+-- Ранее попытки входа записывались в alerts с типом alert_login.
+-- Условие на пустую login_events защищает от повторного копирования,
+-- если миграция прервалась после этого шага.
+INSERT INTO login_events (event_id, date, remote_addr, username, password, auth_status)
+SELECT generateUUIDv4(), date, remote_addr, ifNull(username, ''), ifNull(password, ''), ifNull(auth_status, '')
+FROM alerts
+WHERE type = 'alert_login' AND (SELECT count() FROM login_events) = 0;
+
+ALTER TABLE alerts DELETE WHERE type = 'alert_login' AND (SELECT count() FROM login_events) = 0;
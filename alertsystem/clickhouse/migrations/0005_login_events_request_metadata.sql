@@ -0,0 +1,7 @@
+-- Полные параметры HTTP-запроса из nginx: по ним опознаются инструменты атакующих
+ALTER TABLE login_events
+    ADD COLUMN IF NOT EXISTS method LowCardinality(String) AFTER referer,
+    ADD COLUMN IF NOT EXISTS query String AFTER request_path,
+    ADD COLUMN IF NOT EXISTS protocol LowCardinality(String) AFTER query,
+    ADD COLUMN IF NOT EXISTS request_time Float64 AFTER body_bytes_sent,
+    ADD COLUMN IF NOT EXISTS x_forwarded_for String AFTER request_time;
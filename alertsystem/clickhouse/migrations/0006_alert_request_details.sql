@@ -0,0 +1,4 @@
+-- Путь запроса и найденный признак атаки для правил веб-атак
+ALTER TABLE alerts
+    ADD COLUMN IF NOT EXISTS request_path String AFTER common_password,
+    ADD COLUMN IF NOT EXISTS details String AFTER request_path;
@@ -0,0 +1,2 @@
+-- Примеры значений, по которым сработало правило (пути при сканировании каталогов)
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS samples Array(String) AFTER details;
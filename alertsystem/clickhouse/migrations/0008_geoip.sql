@@ -0,0 +1,16 @@
+-- Местоположение и сеть адреса по базам GeoIP; пустые, если базы не заданы
+ALTER TABLE alerts
+    ADD COLUMN IF NOT EXISTS country LowCardinality(String),
+    ADD COLUMN IF NOT EXISTS city String AFTER country,
+    ADD COLUMN IF NOT EXISTS latitude Float64 AFTER city,
+    ADD COLUMN IF NOT EXISTS longitude Float64 AFTER latitude,
+    ADD COLUMN IF NOT EXISTS asn UInt32 AFTER longitude,
+    ADD COLUMN IF NOT EXISTS as_org String AFTER asn;
+
+ALTER TABLE login_events
+    ADD COLUMN IF NOT EXISTS country LowCardinality(String),
+    ADD COLUMN IF NOT EXISTS city String AFTER country,
+    ADD COLUMN IF NOT EXISTS latitude Float64 AFTER city,
+    ADD COLUMN IF NOT EXISTS longitude Float64 AFTER latitude,
+    ADD COLUMN IF NOT EXISTS asn UInt32 AFTER longitude,
+    ADD COLUMN IF NOT EXISTS as_org String AFTER asn;
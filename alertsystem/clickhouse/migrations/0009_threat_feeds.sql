@@ -0,0 +1,4 @@
+-- Списки репутации (threat intel), в которые входит адрес клиента
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS threat_feeds Array(LowCardinality(String));
+
+ALTER TABLE login_events ADD COLUMN IF NOT EXISTS threat_feeds Array(LowCardinality(String));
@@ -0,0 +1,4 @@
+-- Имя хоста клиента по PTR-записи
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS hostname String;
+
+ALTER TABLE login_events ADD COLUMN IF NOT EXISTS hostname String;
@@ -0,0 +1,2 @@
+-- Доверенные прокси между клиентом и nginx; remote_addr - адрес клиента из X-Forwarded-For
+ALTER TABLE login_events ADD COLUMN IF NOT EXISTS proxy_chain Array(String) AFTER x_forwarded_for;
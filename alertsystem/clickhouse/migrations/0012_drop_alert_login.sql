@@ -0,0 +1,3 @@
+-- В 0004 удаление alert_login из alerts не выполнялось: к этому шагу
+-- login_events уже заполнена. Попытки входа оставались в обеих таблицах.
+ALTER TABLE alerts DELETE WHERE type = 'alert_login';
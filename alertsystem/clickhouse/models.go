@@ -1,13 +1,64 @@
 package clickhouse
 
+import "time"
+
+// Alert - срабатывание правила обнаружения
 type Alert struct {
-	Type           string
-	Date           string
-	RemoteAddr     string
-	Action         string
-	Username       string
-	Password       string
-	AuthStatus     string
-	Count          int
-	CommonPassword string
-}
\ No newline at end of file
+	Type           string    `json:"type"`
+	Severity       string    `json:"severity"`
+	Date           time.Time `json:"date"`
+	RemoteAddr     string    `json:"remote_addr"`
+	Action         string    `json:"action"`
+	Username       string    `json:"username,omitempty"`
+	Password       string    `json:"password,omitempty"`
+	AuthStatus     string    `json:"auth_status,omitempty"`
+	Count          int       `json:"count,omitempty"`
+	CommonPassword string    `json:"common_password,omitempty"`
+	RequestPath    string    `json:"request_path,omitempty"`
+	Details        string    `json:"details,omitempty"`
+	Samples        []string  `json:"samples,omitempty"`
+	// EventIDs - события из login_events, по которым сработало правило
+	EventIDs []string `json:"event_ids,omitempty"`
+	Geo
+	// ThreatFeeds - списки репутации, в которые входит адрес
+	ThreatFeeds []string `json:"threat_feeds,omitempty"`
+	// Hostname - имя хоста по PTR-записи, если к моменту записи оно было известно
+	Hostname string `json:"hostname,omitempty"`
+}
+
+// LoginEvent - попытка входа, записываемая в login_events независимо от правил
+type LoginEvent struct {
+	EventID       string    `json:"event_id"`
+	Date          time.Time `json:"date"`
+	RemoteAddr    string    `json:"remote_addr"`
+	UserAgent     string    `json:"user_agent,omitempty"`
+	Referer       string    `json:"referer,omitempty"`
+	Method        string    `json:"method,omitempty"`
+	RequestPath   string    `json:"request_path,omitempty"`
+	Query         string    `json:"query,omitempty"`
+	Protocol      string    `json:"protocol,omitempty"`
+	BodyBytesSent int64     `json:"body_bytes_sent,omitempty"`
+	// RequestTime - время обработки запроса nginx в секундах
+	RequestTime  float64 `json:"request_time,omitempty"`
+	ForwardedFor string  `json:"forwarded_for,omitempty"`
+	Username     string  `json:"username"`
+	Password     string  `json:"password"`
+	AuthStatus   string  `json:"auth_status"`
+	HTTPStatus   string  `json:"http_status,omitempty"`
+	Correlated   bool    `json:"correlated"`
+	Geo
+	ThreatFeeds []string `json:"threat_feeds,omitempty"`
+	Hostname    string   `json:"hostname,omitempty"`
+	// ProxyChain - доверенные прокси между клиентом и nginx
+	ProxyChain []string `json:"proxy_chain,omitempty"`
+}
+
+// Geo - местоположение и сеть адреса по базам GeoIP, пустые без баз
+type Geo struct {
+	Country   string  `json:"country,omitempty"`
+	City      string  `json:"city,omitempty"`
+	Latitude  float64 `json:"latitude,omitempty"`
+	Longitude float64 `json:"longitude,omitempty"`
+	ASN       uint32  `json:"asn,omitempty"`
+	ASOrg     string  `json:"as_org,omitempty"`
+}
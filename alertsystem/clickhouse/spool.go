@@ -0,0 +1,349 @@
+package clickhouse
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SpoolConfig - параметры дискового буфера записей на время недоступности ClickHouse
+type SpoolConfig struct {
+	// Dir - каталог буфера; пустое значение отключает буфер, и записи,
+	// не отправленные после всех повторов, теряются
+	Dir string `yaml:"dir"`
+	// MaxSizeMB - предельный размер буфера; сверх него новые записи отбрасываются
+	MaxSizeMB int64 `yaml:"max_size_mb"`
+	// SegmentSizeMB - размер одного файла буфера
+	SegmentSizeMB int64 `yaml:"segment_size_mb"`
+	// RetryInterval - как часто пытаться отправить накопленное
+	RetryInterval time.Duration `yaml:"retry_interval"`
+}
+
+func DefaultSpoolConfig() SpoolConfig {
+	return SpoolConfig{
+		Dir:           "state/spool",
+		MaxSizeMB:     512,
+		SegmentSizeMB: 16,
+		RetryInterval: 10 * time.Second,
+	}
+}
+
+func (c SpoolConfig) Validate() error {
+	if c.Dir == "" {
+		return nil
+	}
+	var errs []error
+	if c.MaxSizeMB <= 0 {
+		errs = append(errs, fmt.Errorf("max_size_mb must be positive, got %d", c.MaxSizeMB))
+	}
+	if c.SegmentSizeMB <= 0 || c.SegmentSizeMB > c.MaxSizeMB {
+		errs = append(errs, fmt.Errorf("segment_size_mb must be positive and not exceed max_size_mb, got %d", c.SegmentSizeMB))
+	}
+	if c.RetryInterval <= 0 {
+		errs = append(errs, fmt.Errorf("retry_interval must be positive, got %s", c.RetryInterval))
+	}
+	return errors.Join(errs...)
+}
+
+const (
+	megabyte      = 1 << 20
+	segmentSuffix = ".jsonl"
+)
+
+var (
+	spoolRecords = expvar.NewInt("clickhouse_spool_records")
+	spoolBytes   = expvar.NewInt("clickhouse_spool_bytes")
+)
+
+type segment struct {
+	seq   int64
+	size  int64
+	count int
+	// sealed - сегмент с прошлого запуска; в него не дописываем, так как
+	// последняя строка могла остаться недописанной
+	sealed bool
+}
+
+// Spool - журнал записей на диске. Записи дописываются в конец последнего
+// сегмента и отправляются начиная с самого старого, поэтому порядок сохраняется.
+type Spool struct {
+	dir           string
+	maxBytes      int64
+	segmentBytes  int64
+	RetryInterval time.Duration
+	mu            sync.Mutex
+	segments      []segment
+}
+
+// OpenSpool открывает каталог буфера и учитывает записи, оставшиеся с прошлого запуска
+func OpenSpool(cfg SpoolConfig) (*Spool, error) {
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %w", err)
+	}
+
+	s := &Spool{
+		dir:           cfg.Dir,
+		maxBytes:      cfg.MaxSizeMB * megabyte,
+		segmentBytes:  cfg.SegmentSizeMB * megabyte,
+		RetryInterval: cfg.RetryInterval,
+	}
+
+	entries, err := os.ReadDir(cfg.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spool directory: %w", err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		seq, err := strconv.ParseInt(strings.TrimSuffix(name, segmentSuffix), 10, 64)
+		if err != nil || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+		records, size, err := s.readSegment(seq)
+		if err != nil {
+			return nil, err
+		}
+		if len(records) == 0 {
+			os.Remove(s.segmentPath(seq))
+			continue
+		}
+		s.segments = append(s.segments, segment{seq: seq, size: size, count: len(records), sealed: true})
+	}
+	sort.Slice(s.segments, func(i, j int) bool {
+		return s.segments[i].seq < s.segments[j].seq
+	})
+
+	s.updateMetrics()
+
+	if n := s.Len(); n > 0 {
+		log.Printf("Spool %s contains %d records from previous run", s.dir, n)
+	}
+	return s, nil
+}
+
+// Len возвращает число записей в буфере
+func (s *Spool) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, seg := range s.segments {
+		n += seg.count
+	}
+	return n
+}
+
+// Append дописывает записи в буфер и возвращает число записанных.
+// Записи, не поместившиеся в MaxSizeMB, отбрасываются с ошибкой.
+func (s *Spool) Append(records []Record) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.updateMetrics()
+
+	var full error
+	var lines [][]byte
+	total := s.totalBytes()
+	for _, record := range records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return 0, fmt.Errorf("failed to encode spooled record: %w", err)
+		}
+		line = append(line, '\n')
+
+		if total+int64(len(line)) > s.maxBytes {
+			full = fmt.Errorf("spool %s is full (%d MB)", s.dir, s.maxBytes/megabyte)
+			break
+		}
+		lines = append(lines, line)
+		total += int64(len(line))
+	}
+
+	written, err := s.write(lines)
+	if err != nil {
+		return written, err
+	}
+	return written, full
+}
+
+// Replay отправляет записи начиная с самых старых порциями не больше limit.
+// Каждая порция содержит записи одного вида, чтобы их можно было отправить
+// одним запросом. Отправленные записи удаляются из буфера; при первой ошибке
+// send отправка прекращается, а неотправленные остаются на диске.
+func (s *Spool) Replay(limit int, send func([]Record) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.updateMetrics()
+
+	for len(s.segments) > 0 {
+		seq := s.segments[0].seq
+		records, _, err := s.readSegment(seq)
+		if err != nil {
+			return err
+		}
+
+		sent := 0
+		for sent < len(records) {
+			end := sent + 1
+			for end < len(records) && end-sent < limit && records[end].kind() == records[sent].kind() {
+				end++
+			}
+			if err := send(records[sent:end]); err != nil {
+				if sent > 0 {
+					if rerr := s.rewriteSegment(0, records[sent:]); rerr != nil {
+						return errors.Join(err, rerr)
+					}
+				}
+				return err
+			}
+			sent = end
+		}
+
+		if err := os.Remove(s.segmentPath(seq)); err != nil {
+			return fmt.Errorf("failed to remove spool segment: %w", err)
+		}
+		s.segments = s.segments[1:]
+	}
+	return nil
+}
+
+// write дописывает строки в сегменты, открывая новый при превышении SegmentSizeMB,
+// и возвращает число записанных строк
+func (s *Spool) write(lines [][]byte) (int, error) {
+	written := 0
+	for written < len(lines) {
+		last := len(s.segments) - 1
+		if last < 0 || s.segments[last].sealed ||
+			s.segments[last].size+int64(len(lines[written])) > s.segmentBytes {
+			var seq int64 = 1
+			if last >= 0 {
+				seq = s.segments[last].seq + 1
+			}
+			s.segments = append(s.segments, segment{seq: seq})
+			last++
+		}
+		seg := &s.segments[last]
+
+		// Набираем строки, помещающиеся в текущий сегмент (хотя бы одну)
+		var data []byte
+		n := 0
+		for written+n < len(lines) {
+			line := lines[written+n]
+			if n > 0 && seg.size+int64(len(data)+len(line)) > s.segmentBytes {
+				break
+			}
+			data = append(data, line...)
+			n++
+		}
+
+		if err := appendFile(s.segmentPath(seg.seq), data); err != nil {
+			return written, err
+		}
+		seg.size += int64(len(data))
+		seg.count += n
+		written += n
+	}
+	return written, nil
+}
+
+// appendFile дописывает данные и дожидается их записи на диск
+func appendFile(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open spool segment: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(data); err != nil {
+		return fmt.Errorf("failed to write spool segment: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync spool segment: %w", err)
+	}
+	return nil
+}
+
+// readSegment читает записи сегмента. Поврежденные строки (например,
+// недописанная при сбое последняя строка) пропускаются.
+func (s *Spool) readSegment(seq int64) ([]Record, int64, error) {
+	file, err := os.Open(s.segmentPath(seq))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open spool segment: %w", err)
+	}
+	defer file.Close()
+
+	var records []Record
+	var size int64
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), megabyte)
+	for scanner.Scan() {
+		size += int64(len(scanner.Bytes())) + 1
+
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil || (record.Alert == nil) == (record.LoginEvent == nil) {
+			log.Printf("Skipping corrupted record in spool segment %d", seq)
+			continue
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read spool segment: %w", err)
+	}
+	return records, size, nil
+}
+
+// rewriteSegment заменяет содержимое сегмента через временный файл
+func (s *Spool) rewriteSegment(index int, records []Record) error {
+	seg := &s.segments[index]
+
+	var data []byte
+	for _, record := range records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("failed to encode spooled record: %w", err)
+		}
+		data = append(data, line...)
+		data = append(data, '\n')
+	}
+
+	path := s.segmentPath(seg.seq)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write spool segment: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to replace spool segment: %w", err)
+	}
+
+	seg.size = int64(len(data))
+	seg.count = len(records)
+	return nil
+}
+
+func (s *Spool) segmentPath(seq int64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%012d%s", seq, segmentSuffix))
+}
+
+func (s *Spool) totalBytes() int64 {
+	var total int64
+	for _, seg := range s.segments {
+		total += seg.size
+	}
+	return total
+}
+
+func (s *Spool) updateMetrics() {
+	count := 0
+	for _, seg := range s.segments {
+		count += seg.count
+	}
+	spoolRecords.Set(int64(count))
+	spoolBytes.Set(s.totalBytes())
+}
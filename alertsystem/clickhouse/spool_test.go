@@ -0,0 +1,187 @@
+package clickhouse
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func testSpool(t *testing.T, dir string) *Spool {
+	t.Helper()
+	s, err := OpenSpool(SpoolConfig{Dir: dir, MaxSizeMB: 1, SegmentSizeMB: 1, RetryInterval: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// testRecords создает записи a0, a1... для алертов и e0, e1... для событий входа
+// по шаблону kinds: 'a' - алерт, 'e' - событие
+func testRecords(kinds string, from int) []Record {
+	records := make([]Record, len(kinds))
+	for i, kind := range kinds {
+		id := fmt.Sprintf("%c%d", kind, from+i)
+		if kind == 'e' {
+			records[i] = Record{LoginEvent: &LoginEvent{EventID: id}}
+		} else {
+			records[i] = Record{Alert: &Alert{Type: id}}
+		}
+	}
+	return records
+}
+
+func recordIDs(records []Record) []string {
+	ids := make([]string, len(records))
+	for i, record := range records {
+		if record.LoginEvent != nil {
+			ids[i] = record.LoginEvent.EventID
+		} else {
+			ids[i] = record.Alert.Type
+		}
+	}
+	return ids
+}
+
+func segmentFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*"+segmentSuffix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range files {
+		files[i] = filepath.Base(files[i])
+	}
+	return files
+}
+
+func TestSpoolRotationAndOrder(t *testing.T) {
+	dir := t.TempDir()
+	s := testSpool(t, dir)
+	// Примерно по три записи на сегмент
+	line := int64(len(`{"alert":{"type":"a0","severity":"","date":"0001-01-01T00:00:00Z","remote_addr":"","action":""}}`) + 1)
+	s.segmentBytes = 3 * line
+
+	if n, err := s.Append(testRecords("aaaaa", 0)); n != 5 || err != nil {
+		t.Fatalf("Append = %d, %v", n, err)
+	}
+	if n, err := s.Append(testRecords("aaaaa", 5)); n != 5 || err != nil {
+		t.Fatalf("Append = %d, %v", n, err)
+	}
+	if got := segmentFiles(t, dir); len(got) != 4 {
+		t.Errorf("segments = %v, want 4 files", got)
+	}
+	if s.Len() != 10 {
+		t.Errorf("Len() = %d, want 10", s.Len())
+	}
+
+	var got []string
+	if err := s.Replay(100, func(records []Record) error {
+		got = append(got, recordIDs(records)...)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	want := recordIDs(testRecords("aaaaaaaaaa", 0))
+	if !slices.Equal(got, want) {
+		t.Errorf("replayed %v, want %v", got, want)
+	}
+	if files := segmentFiles(t, dir); len(files) != 0 || s.Len() != 0 {
+		t.Errorf("after replay: files %v, Len() = %d", files, s.Len())
+	}
+}
+
+func TestSpoolReplayBatches(t *testing.T) {
+	s := testSpool(t, t.TempDir())
+	s.Append(testRecords("aaaeeaaaaae", 0))
+
+	var batches [][]string
+	s.Replay(3, func(records []Record) error {
+		batches = append(batches, recordIDs(records))
+		return nil
+	})
+
+	// Порции не смешивают виды записей и не превышают limit
+	want := [][]string{{"a0", "a1", "a2"}, {"e3", "e4"}, {"a5", "a6", "a7"}, {"a8", "a9"}, {"e10"}}
+	if len(batches) != len(want) {
+		t.Fatalf("batches = %v, want %v", batches, want)
+	}
+	for i := range want {
+		if !slices.Equal(batches[i], want[i]) {
+			t.Errorf("batch %d = %v, want %v", i, batches[i], want[i])
+		}
+	}
+}
+
+func TestSpoolPartialReplayAndRestart(t *testing.T) {
+	dir := t.TempDir()
+	s := testSpool(t, dir)
+	s.Append(testRecords("aaaa", 0))
+
+	// Вторая порция не отправлена: отправленная удаляется, остальное остается
+	unavailable := errors.New("unavailable")
+	calls := 0
+	err := s.Replay(2, func(records []Record) error {
+		calls++
+		if calls == 2 {
+			return unavailable
+		}
+		return nil
+	})
+	if !errors.Is(err, unavailable) {
+		t.Fatalf("Replay error = %v, want %v", err, unavailable)
+	}
+	if s.Len() != 2 {
+		t.Fatalf("Len() after partial replay = %d, want 2", s.Len())
+	}
+
+	// Недописанная при сбое строка пропускается при открытии
+	path := s.segmentPath(s.segments[0].seq)
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"alert":{"type":"a9`)
+	file.Close()
+
+	// После перезапуска старый сегмент не дописывается, новые записи идут следом
+	s = testSpool(t, dir)
+	if s.Len() != 2 {
+		t.Fatalf("Len() after restart = %d, want 2", s.Len())
+	}
+	s.Append(testRecords("ee", 4))
+	if files := segmentFiles(t, dir); len(files) != 2 {
+		t.Errorf("segments after restart = %v, want 2 files", files)
+	}
+
+	var got []string
+	if err := s.Replay(10, func(records []Record) error {
+		got = append(got, recordIDs(records)...)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a2", "a3", "e4", "e5"}; !slices.Equal(got, want) {
+		t.Errorf("replayed %v, want %v", got, want)
+	}
+}
+
+func TestSpoolFull(t *testing.T) {
+	s := testSpool(t, t.TempDir())
+	line := int64(len(`{"alert":{"type":"a0","severity":"","date":"0001-01-01T00:00:00Z","remote_addr":"","action":""}}`) + 1)
+	s.maxBytes = 3 * line
+
+	n, err := s.Append(testRecords("aaaaa", 0))
+	if n != 3 || err == nil {
+		t.Errorf("Append = %d, %v; want 3 and an error", n, err)
+	}
+	if n, err := s.Append(testRecords("a", 5)); n != 0 || err == nil {
+		t.Errorf("Append to full spool = %d, %v; want 0 and an error", n, err)
+	}
+	if s.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", s.Len())
+	}
+}
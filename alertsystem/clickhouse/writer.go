@@ -0,0 +1,391 @@
+package clickhouse
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+)
+
+// WriterConfig - параметры асинхронной записи алертов
+type WriterConfig struct {
+	// BatchSize - размер батча, при достижении которого он отправляется сразу
+	BatchSize int `yaml:"batch_size"`
+	// FlushInterval - как часто отправлять неполный батч
+	FlushInterval time.Duration `yaml:"flush_interval"`
+	// BufferSize - размер очереди; при заполнении запись блокируется,
+	// и чтение логов приостанавливается до освобождения места
+	BufferSize int `yaml:"buffer_size"`
+	// MaxRetries - число повторов при временных ошибках
+	MaxRetries int `yaml:"max_retries"`
+	// RetryBackoff - задержка перед первым повтором, затем удваивается до MaxBackoff
+	RetryBackoff time.Duration `yaml:"retry_backoff"`
+	MaxBackoff   time.Duration `yaml:"max_backoff"`
+}
+
+func DefaultWriterConfig() WriterConfig {
+	return WriterConfig{
+		BatchSize:     500,
+		FlushInterval: 1 * time.Second,
+		BufferSize:    10000,
+		MaxRetries:    5,
+		RetryBackoff:  500 * time.Millisecond,
+		MaxBackoff:    30 * time.Second,
+	}
+}
+
+func (c WriterConfig) Validate() error {
+	var errs []error
+	if c.BatchSize <= 0 {
+		errs = append(errs, fmt.Errorf("batch_size must be positive, got %d", c.BatchSize))
+	}
+	if c.FlushInterval <= 0 {
+		errs = append(errs, fmt.Errorf("flush_interval must be positive, got %s", c.FlushInterval))
+	}
+	if c.BufferSize <= 0 {
+		errs = append(errs, fmt.Errorf("buffer_size must be positive, got %d", c.BufferSize))
+	}
+	if c.MaxRetries < 0 {
+		errs = append(errs, fmt.Errorf("max_retries must not be negative, got %d", c.MaxRetries))
+	}
+	if c.RetryBackoff <= 0 {
+		errs = append(errs, fmt.Errorf("retry_backoff must be positive, got %s", c.RetryBackoff))
+	}
+	if c.MaxBackoff < c.RetryBackoff {
+		errs = append(errs, fmt.Errorf("max_backoff must not be less than retry_backoff, got %s", c.MaxBackoff))
+	}
+	return errors.Join(errs...)
+}
+
+// Счетчики по видам записей: alerts и login_events
+var (
+	writerWritten = expvar.NewMap("clickhouse_written")
+	writerDropped = expvar.NewMap("clickhouse_dropped")
+	writerSpooled = expvar.NewMap("clickhouse_spooled")
+	writerRetries = expvar.NewInt("clickhouse_insert_retries")
+)
+
+// queuedWriter - последний созданный writer, его очередь показывает clickhouse_queue_depth.
+// Имя в expvar публикуется один раз: повторная публикация вызывает панику.
+var queuedWriter atomic.Pointer[Writer]
+
+func init() {
+	expvar.Publish("clickhouse_queue_depth", expvar.Func(func() any {
+		if w := queuedWriter.Load(); w != nil {
+			return len(w.queue)
+		}
+		return 0
+	}))
+}
+
+var errWriterClosed = errors.New("clickhouse writer is closed")
+
+// Record - запись для ClickHouse: алерт или событие входа (заполнено одно поле)
+type Record struct {
+	Alert      *Alert      `json:"alert,omitempty"`
+	LoginEvent *LoginEvent `json:"login_event,omitempty"`
+}
+
+// kind возвращает имя таблицы записи
+func (r Record) kind() string {
+	if r.LoginEvent != nil {
+		return "login_events"
+	}
+	return "alerts"
+}
+
+// Writer накапливает алерты и события входа и отправляет их батчами по размеру
+// или по таймеру. Если ClickHouse недоступен, батчи откладываются в spool
+// и отправляются позже в том же порядке.
+type Writer struct {
+	client *Client
+	cfg    WriterConfig
+	spool  *Spool
+	// lastDrain - время последней попытки отправить содержимое spool
+	lastDrain time.Time
+	queue     chan Record
+	// mu защищает queue от закрытия во время отправки в нее
+	mu     sync.RWMutex
+	closed bool
+	done   chan struct{}
+}
+
+// NewWriter создает писателя; spool может быть nil, тогда записи,
+// не отправленные после всех повторов, теряются
+func NewWriter(client *Client, cfg WriterConfig, spool *Spool) *Writer {
+	w := &Writer{
+		client: client,
+		cfg:    cfg,
+		spool:  spool,
+		queue:  make(chan Record, cfg.BufferSize),
+		done:   make(chan struct{}),
+	}
+	queuedWriter.Store(w)
+	go w.run()
+	return w
+}
+
+func (w *Writer) InsertAlert(ctx context.Context, alert Alert) error {
+	return w.enqueue(ctx, Record{Alert: &alert})
+}
+
+func (w *Writer) InsertLoginEvent(ctx context.Context, event LoginEvent) error {
+	return w.enqueue(ctx, Record{LoginEvent: &event})
+}
+
+// enqueue ставит запись в очередь. При заполненной очереди вызов блокируется
+// до освобождения места или отмены ctx - так наблюдатель перестает читать логи,
+// пока ClickHouse не справится с накопленным.
+func (w *Writer) enqueue(ctx context.Context, record Record) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.closed {
+		return errWriterClosed
+	}
+
+	// Сначала без ожидания: при отмененном ctx select выбирает ветку
+	// случайно и мог бы отбросить запись, хотя в очереди есть место
+	select {
+	case w.queue <- record:
+		return nil
+	default:
+	}
+	select {
+	case w.queue <- record:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close отправляет оставшиеся записи и закрывает клиент.
+// Не отправленное остается в spool до следующего запуска.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.mu.Unlock()
+
+	<-w.done
+	return w.client.Close()
+}
+
+func (w *Writer) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Record, 0, w.cfg.BatchSize)
+	for {
+		select {
+		case record, ok := <-w.queue:
+			if !ok {
+				w.flush(batch)
+				w.drain(true)
+				return
+			}
+			batch = append(batch, record)
+			if len(batch) >= w.cfg.BatchSize {
+				w.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			w.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+// flush отправляет батч, по отдельности для каждой таблицы.
+// Если в spool уже есть записи, батч дописывается за ними, чтобы не нарушить порядок.
+func (w *Writer) flush(batch []Record) {
+	if !w.drain(false) {
+		w.toSpool(batch)
+		return
+	}
+
+	var alerts, events []Record
+	for _, record := range batch {
+		if record.LoginEvent != nil {
+			events = append(events, record)
+		} else {
+			alerts = append(alerts, record)
+		}
+	}
+	w.insertWithRetry(alerts)
+	w.insertWithRetry(events)
+}
+
+// insertWithRetry отправляет записи одного вида, повторяя попытки при временных
+// ошибках. Пока идут повторы, очередь не разбирается, что и создает обратное давление.
+func (w *Writer) insertWithRetry(records []Record) {
+	if len(records) == 0 {
+		return
+	}
+	kind := records[0].kind()
+
+	backoff := w.cfg.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := w.insert(records)
+		if err == nil {
+			writerWritten.Add(kind, int64(len(records)))
+			return
+		}
+
+		if !isTransient(err) {
+			writerDropped.Add(kind, int64(len(records)))
+			log.Printf("Failed to insert %d %s into ClickHouse, dropping: %v", len(records), kind, err)
+			return
+		}
+
+		if attempt >= w.cfg.MaxRetries {
+			log.Printf("Failed to insert %d %s into ClickHouse after %d retries: %v", len(records), kind, attempt, err)
+			w.toSpool(records)
+			return
+		}
+
+		writerRetries.Add(1)
+		log.Printf("Failed to insert %d %s into ClickHouse, retrying in %s: %v", len(records), kind, backoff, err)
+		time.Sleep(backoff)
+		backoff = min(backoff*2, w.cfg.MaxBackoff)
+	}
+}
+
+// insert отправляет записи одного вида в соответствующую таблицу
+func (w *Writer) insert(records []Record) error {
+	ctx := context.Background()
+	if records[0].LoginEvent != nil {
+		events := make([]LoginEvent, len(records))
+		for i, record := range records {
+			events[i] = *record.LoginEvent
+		}
+		return w.client.InsertLoginEvents(ctx, events)
+	}
+
+	alerts := make([]Alert, len(records))
+	for i, record := range records {
+		alerts[i] = *record.Alert
+	}
+	return w.client.InsertAlerts(ctx, alerts)
+}
+
+// drain отправляет содержимое spool не чаще RetryInterval (force - без ожидания).
+// Возвращает true, если spool пуст и новые батчи можно отправлять напрямую.
+func (w *Writer) drain(force bool) bool {
+	if w.spool == nil || w.spool.Len() == 0 {
+		return true
+	}
+	if !force && time.Since(w.lastDrain) < w.spool.RetryInterval {
+		return false
+	}
+	w.lastDrain = time.Now()
+
+	pending := w.spool.Len()
+	err := w.spool.Replay(w.cfg.BatchSize, func(records []Record) error {
+		kind := records[0].kind()
+		err := w.insert(records)
+		if err != nil && !isTransient(err) {
+			// Повтор не поможет, иначе эти записи навсегда заблокируют spool
+			writerDropped.Add(kind, int64(len(records)))
+			log.Printf("Failed to insert %d spooled %s into ClickHouse, dropping: %v", len(records), kind, err)
+			return nil
+		}
+		if err == nil {
+			writerWritten.Add(kind, int64(len(records)))
+		}
+		return err
+	})
+	if err != nil {
+		log.Printf("ClickHouse is still unavailable, %d records remain in spool: %v", w.spool.Len(), err)
+		return false
+	}
+
+	log.Printf("Replayed %d spooled records into ClickHouse", pending)
+	return true
+}
+
+// toSpool откладывает записи на диск; без spool они теряются
+func (w *Writer) toSpool(batch []Record) {
+	if len(batch) == 0 {
+		return
+	}
+	if w.spool == nil {
+		countByKind(writerDropped, batch)
+		log.Printf("Dropping %d records: ClickHouse is unavailable and spool is disabled", len(batch))
+		return
+	}
+
+	written, err := w.spool.Append(batch)
+	countByKind(writerSpooled, batch[:written])
+	if err != nil {
+		countByKind(writerDropped, batch[written:])
+		log.Printf("Failed to spool %d records, dropping: %v", len(batch)-written, err)
+	}
+}
+
+func countByKind(counter *expvar.Map, records []Record) {
+	for _, record := range records {
+		counter.Add(record.kind(), 1)
+	}
+}
+
+// permanentError - ошибка, повтор которой не поможет (например, несовместимые данные)
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+func permanent(err error) error {
+	return permanentError{err: err}
+}
+
+// Коды ошибок ClickHouse, после которых имеет смысл повторить запрос.
+// Ошибки схемы тоже временные: таблица или столбец появятся после миграции,
+// а до этого записи должны дождаться ее в spool, а не потеряться.
+var transientCodes = map[int32]bool{
+	16:  true, // NO_SUCH_COLUMN_IN_TABLE
+	47:  true, // UNKNOWN_IDENTIFIER
+	60:  true, // UNKNOWN_TABLE
+	81:  true, // UNKNOWN_DATABASE
+	159: true, // TIMEOUT_EXCEEDED
+	164: true, // READONLY
+	202: true, // TOO_MANY_SIMULTANEOUS_QUERIES
+	209: true, // SOCKET_TIMEOUT
+	210: true, // NETWORK_ERROR
+	241: true, // MEMORY_LIMIT_EXCEEDED
+	252: true, // TOO_MANY_PARTS
+	285: true, // TOO_FEW_LIVE_REPLICAS
+	319: true, // UNKNOWN_STATUS_OF_INSERT
+	425: true, // SYSTEM_ERROR
+}
+
+// isTransient определяет, стоит ли повторять вставку. Сетевые ошибки
+// считаются временными, ошибки сервера - только из списка transientCodes.
+func isTransient(err error) bool {
+	var perm permanentError
+	if errors.As(err, &perm) {
+		return false
+	}
+	var exception *clickhouse.Exception
+	if errors.As(err, &exception) {
+		return transientCodes[exception.Code]
+	}
+	return true
+}
@@ -0,0 +1,34 @@
+package clickhouse
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestEnqueueCancelledContext(t *testing.T) {
+	// Фоновая отправка не запущена: очередь никто не читает
+	w := &Writer{queue: make(chan Record, 100)}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// При свободном месте запись попадает в очередь даже после отмены ctx
+	for i := 0; i < cap(w.queue); i++ {
+		if err := w.InsertAlert(ctx, Alert{Type: "bruteforce"}); err != nil {
+			t.Fatalf("insert %d: %v", i, err)
+		}
+	}
+	if len(w.queue) != cap(w.queue) {
+		t.Fatalf("queued %d records, want %d", len(w.queue), cap(w.queue))
+	}
+
+	// Очередь заполнена: ожидание прерывается отменой ctx
+	if err := w.InsertLoginEvent(ctx, LoginEvent{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("full queue: got %v, want context.Canceled", err)
+	}
+
+	w.closed = true
+	if err := w.InsertAlert(context.Background(), Alert{}); !errors.Is(err, errWriterClosed) {
+		t.Fatalf("closed writer: got %v, want errWriterClosed", err)
+	}
+}
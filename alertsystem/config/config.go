@@ -0,0 +1,202 @@
+package config
+
+import (
+	"alertsystem/clickhouse"
+	"alertsystem/geoip"
+	"alertsystem/parser"
+	"alertsystem/rdns"
+	"alertsystem/rules"
+	"alertsystem/threatintel"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config - конфигурация alertsystem, загружаемая при старте.
+// Поддерживаются YAML и JSON (JSON является подмножеством YAML).
+type Config struct {
+	Rules       map[string]rules.Config `yaml:"rules"`
+	Correlation CorrelationConfig       `yaml:"correlation"`
+	Watcher     WatcherConfig           `yaml:"watcher"`
+	Sources     []SourceConfig          `yaml:"sources"`
+	EventTime   EventTimeConfig         `yaml:"event_time"`
+	Metrics     MetricsConfig           `yaml:"metrics"`
+	Writer      clickhouse.WriterConfig `yaml:"writer"`
+	Spool       clickhouse.SpoolConfig  `yaml:"spool"`
+	GeoIP       geoip.Config            `yaml:"geoip"`
+	ThreatIntel threatintel.Config      `yaml:"threat_intel"`
+	ReverseDNS  rdns.Config             `yaml:"rdns"`
+	// TrustedProxies - сети балансировщиков и CDN перед nginx, чьим заголовкам
+	// X-Forwarded-For и X-Real-IP верится при определении адреса клиента.
+	// Применяются только при запуске, как и источники.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+// MetricsConfig - HTTP-адрес для счетчиков expvar (/debug/vars); пустое значение отключает
+type MetricsConfig struct {
+	Listen string `yaml:"listen"`
+}
+
+// EventTimeConfig - параметры оценки правил по времени записей
+type EventTimeConfig struct {
+	// AllowedLateness - насколько запись может отставать от самой поздней
+	// из уже виденных; более поздние записи оцениваются по этой границе
+	AllowedLateness time.Duration `yaml:"allowed_lateness"`
+}
+
+// SourceConfig привязывает набор файлов логов к парсеру
+type SourceConfig struct {
+	Name   string `yaml:"name"`
+	Parser string `yaml:"parser"`
+	// Paths - пути или шаблоны (*.log); шаблон допустим только в имени файла
+	Paths []string `yaml:"paths"`
+}
+
+// CorrelationConfig - параметры сопоставления записей nginx и веб-приложения
+type CorrelationConfig struct {
+	// Tolerance - допустимая разница времени между записями одной попытки входа
+	Tolerance time.Duration `yaml:"tolerance"`
+	// MaxWait - сколько ждать парную запись, прежде чем выдать событие без нее
+	MaxWait time.Duration `yaml:"max_wait"`
+}
+
+// WatcherConfig - параметры чтения логов. Применяются только при запуске.
+type WatcherConfig struct {
+	// StateFile - файл с позициями чтения логов; пустое значение отключает сохранение
+	StateFile string `yaml:"state_file"`
+	// StartFromEnd - для файлов без сохраненной позиции начинать с конца,
+	// не обрабатывая историю (для новых установок с уже накопленными логами)
+	StartFromEnd bool `yaml:"start_from_end"`
+}
+
+// Default возвращает конфигурацию, в которой все правила работают с параметрами по умолчанию
+func Default() *Config {
+	return &Config{
+		Rules: make(map[string]rules.Config),
+		Correlation: CorrelationConfig{
+			Tolerance: 2 * time.Second,
+			MaxWait:   10 * time.Second,
+		},
+		Watcher: WatcherConfig{
+			StateFile: "state/watcher.json",
+		},
+		Writer:      clickhouse.DefaultWriterConfig(),
+		Spool:       clickhouse.DefaultSpoolConfig(),
+		GeoIP:       geoip.DefaultConfig(),
+		ThreatIntel: threatintel.DefaultConfig(),
+		ReverseDNS:  rdns.DefaultConfig(),
+		EventTime: EventTimeConfig{
+			AllowedLateness: 30 * time.Second,
+		},
+		Sources: []SourceConfig{
+			{Name: "nginx", Parser: parser.SourceNginx, Paths: []string{"../logs/nginx/*access.log"}},
+			{Name: "web", Parser: parser.SourceWeb, Paths: []string{"../logs/web/*.log"}},
+		},
+	}
+}
+
+// Load читает и проверяет конфигурационный файл
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	return Parse(data)
+}
+
+func Parse(data []byte) (*Config, error) {
+	cfg := Default()
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if cfg.Rules == nil {
+		cfg.Rules = make(map[string]rules.Config)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return cfg, nil
+}
+
+func (c *Config) Validate() error {
+	var errs []error
+	if err := rules.ValidateConfigs(c.Rules); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Correlation.Tolerance <= 0 {
+		errs = append(errs, fmt.Errorf("correlation.tolerance must be positive, got %s", c.Correlation.Tolerance))
+	}
+	if c.Correlation.MaxWait <= 0 {
+		errs = append(errs, fmt.Errorf("correlation.max_wait must be positive, got %s", c.Correlation.MaxWait))
+	}
+	if err := c.Writer.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("writer: %w", err))
+	}
+	if err := c.Spool.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("spool: %w", err))
+	}
+	if err := c.GeoIP.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("geoip: %w", err))
+	}
+	if err := c.ThreatIntel.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("threat_intel: %w", err))
+	}
+	if err := c.ReverseDNS.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("rdns: %w", err))
+	}
+	if c.EventTime.AllowedLateness < 0 {
+		errs = append(errs, fmt.Errorf("event_time.allowed_lateness must not be negative, got %s", c.EventTime.AllowedLateness))
+	}
+	if _, err := parser.NewTrustedProxies(c.TrustedProxies); err != nil {
+		errs = append(errs, fmt.Errorf("trusted_proxies: %w", err))
+	}
+	if len(c.Sources) == 0 {
+		errs = append(errs, errors.New("at least one source is required"))
+	}
+	names := make(map[string]bool)
+	for i, source := range c.Sources {
+		if err := source.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("sources[%d]: %w", i, err))
+		}
+		if names[source.Name] {
+			errs = append(errs, fmt.Errorf("sources[%d]: duplicate name %q", i, source.Name))
+		}
+		names[source.Name] = true
+	}
+	return errors.Join(errs...)
+}
+
+func (s SourceConfig) validate() error {
+	var errs []error
+	if s.Name == "" {
+		errs = append(errs, errors.New("name is required"))
+	}
+	if _, ok := parser.Lookup(s.Parser, nil); !ok {
+		errs = append(errs, fmt.Errorf("unknown parser %q, available: %s", s.Parser, strings.Join(parser.Parsers(), ", ")))
+	}
+	if len(s.Paths) == 0 {
+		errs = append(errs, errors.New("at least one path is required"))
+	}
+	for _, path := range s.Paths {
+		if _, err := filepath.Match(path, ""); err != nil {
+			errs = append(errs, fmt.Errorf("bad path pattern %q: %w", path, err))
+			continue
+		}
+		// Наблюдение ведется за каталогом, поэтому шаблон в нем не поддерживается
+		if strings.ContainsAny(filepath.Dir(path), "*?[") {
+			errs = append(errs, fmt.Errorf("path %q: patterns are only supported in the file name", path))
+		}
+	}
+	return errors.Join(errs...)
+}
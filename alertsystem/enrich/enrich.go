@@ -0,0 +1,104 @@
+// Package enrich дополняет записи логов сведениями об адресе клиента
+// из локальных баз. Обогащение выполняется до проверки правилами, поэтому
+// правила и события входа видят уже дополненные записи.
+package enrich
+
+import (
+	"alertsystem/geoip"
+	"alertsystem/parser"
+	"alertsystem/rdns"
+	"alertsystem/threatintel"
+	"context"
+)
+
+type Config struct {
+	GeoIP       geoip.Config
+	ThreatIntel threatintel.Config
+	ReverseDNS  rdns.Config
+}
+
+type Enricher struct {
+	cfg   Config
+	geo   *geoip.DB
+	feeds *threatintel.Feeds
+	rdns  *rdns.Resolver
+}
+
+func New(cfg Config) (*Enricher, error) {
+	geo, err := geoip.Open(cfg.GeoIP)
+	if err != nil {
+		return nil, err
+	}
+	feeds, err := threatintel.Open(cfg.ThreatIntel)
+	if err != nil {
+		geo.Close()
+		return nil, err
+	}
+	return &Enricher{cfg: cfg, geo: geo, feeds: feeds, rdns: rdns.New(cfg.ReverseDNS)}, nil
+}
+
+// Watch перечитывает обновленные базы и списки до отмены ctx
+func (e *Enricher) Watch(ctx context.Context) {
+	go e.feeds.Watch(ctx)
+	e.geo.Watch(ctx, e.cfg.GeoIP.ReloadInterval)
+}
+
+// Enrich возвращает запись, дополненную сведениями об адресе клиента
+func (e *Enricher) Enrich(entry parser.LogEntry) parser.LogEntry {
+	switch log := entry.(type) {
+	case parser.NginxLog:
+		log.Geo = e.Geo(log.RemoteAddr)
+		log.ThreatFeeds = e.ThreatFeeds(log.RemoteAddr)
+		// Имя хоста запрашивается заранее, чтобы к записи события или алерта
+		// оно уже было в кэше
+		e.rdns.Lookup(log.RemoteAddr)
+		return log
+	case parser.WebServiceLog:
+		log.Geo = e.Geo(log.RemoteAddr)
+		log.ThreatFeeds = e.ThreatFeeds(log.RemoteAddr)
+		e.rdns.Lookup(log.RemoteAddr)
+		return log
+	case parser.LoginEvent:
+		if log.Geo == nil {
+			log.Geo = e.Geo(log.RemoteAddr)
+		}
+		if log.ThreatFeeds == nil {
+			log.ThreatFeeds = e.ThreatFeeds(log.RemoteAddr)
+		}
+		return log
+	}
+	return entry
+}
+
+// ThreatFeeds возвращает списки репутации, в которые входит адрес
+func (e *Enricher) ThreatFeeds(remoteAddr string) []string {
+	return e.feeds.Lookup(remoteAddr)
+}
+
+// Hostname возвращает имя хоста адреса, если оно уже разрешено; не ждет DNS
+func (e *Enricher) Hostname(remoteAddr string) string {
+	hostname, _ := e.rdns.Lookup(remoteAddr)
+	return hostname
+}
+
+// Geo определяет местоположение и сеть адреса; nil, если адрес не найден
+func (e *Enricher) Geo(remoteAddr string) *parser.Geo {
+	location, ok := e.geo.Lookup(remoteAddr)
+	if !ok {
+		return nil
+	}
+	return &parser.Geo{
+		Country:        location.Country,
+		City:           location.City,
+		Latitude:       location.Latitude,
+		Longitude:      location.Longitude,
+		HasCoordinates: location.HasCoordinates,
+		ASN:            location.ASN,
+		Org:            location.Org,
+	}
+}
+
+func (e *Enricher) Close() error {
+	e.rdns.Close()
+	return e.geo.Close()
+}
@@ -0,0 +1,241 @@
+// Package geoip определяет местоположение и сеть (ASN) IP-адреса по локальным
+// базам в формате MaxMind (mmdb): GeoLite2-City/ASN, DB-IP City/ASN Lite.
+package geoip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// Config - пути к базам GeoIP; пустой путь отключает соответствующую базу
+type Config struct {
+	CityDB string `yaml:"city_db"`
+	ASNDB  string `yaml:"asn_db"`
+	// ReloadInterval - как часто проверять, не обновились ли файлы баз; 0 отключает
+	ReloadInterval time.Duration `yaml:"reload_interval"`
+}
+
+func DefaultConfig() Config {
+	return Config{ReloadInterval: 1 * time.Minute}
+}
+
+func (c Config) Validate() error {
+	if c.ReloadInterval < 0 {
+		return fmt.Errorf("reload_interval must not be negative, got %s", c.ReloadInterval)
+	}
+	return nil
+}
+
+// Location - местоположение и сеть адреса
+type Location struct {
+	Country string // ISO-код страны
+	City    string
+	// Координаты есть не у всех записей: для части сетей известна только страна
+	Latitude       float64
+	Longitude      float64
+	HasCoordinates bool
+	ASN            uint32
+	Org            string // владелец автономной системы
+}
+
+// Поля записи базы City, остальные не декодируются
+type cityRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	Location struct {
+		Latitude  *float64 `maxminddb:"latitude"`
+		Longitude *float64 `maxminddb:"longitude"`
+	} `maxminddb:"location"`
+}
+
+type asnRecord struct {
+	Number uint32 `maxminddb:"autonomous_system_number"`
+	Org    string `maxminddb:"autonomous_system_organization"`
+}
+
+// database - открытый файл базы и время его изменения для отслеживания обновлений
+type database struct {
+	path    string
+	reader  *maxminddb.Reader
+	modTime time.Time
+	missing bool // об отсутствии файла уже сообщено
+}
+
+// DB - базы City и ASN. Файлы можно заменять на ходу (например, geoipupdate):
+// Reload открывает их заново, не прерывая поиск.
+type DB struct {
+	mu   sync.RWMutex
+	city database
+	asn  database
+}
+
+// Open открывает базы из конфигурации. Без заданных путей возвращается
+// пустая база, в которой адреса не находятся. Отсутствующий файл не ошибка:
+// база загрузится при следующей проверке после его появления.
+func Open(cfg Config) (*DB, error) {
+	db := &DB{
+		city: database{path: cfg.CityDB},
+		asn:  database{path: cfg.ASNDB},
+	}
+	for _, d := range []*database{&db.city, &db.asn} {
+		if err := d.reload(); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+// reload открывает файл заново, если он изменился; старый файл закрывается
+func (d *database) reload() error {
+	if d.path == "" {
+		return nil
+	}
+
+	info, err := os.Stat(d.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		if !d.missing {
+			log.Printf("GeoIP database %s not found, it will be loaded when it appears", d.path)
+			d.missing = true
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stat GeoIP database %s: %w", d.path, err)
+	}
+	if d.reader != nil && info.ModTime().Equal(d.modTime) {
+		return nil
+	}
+
+	reader, err := maxminddb.Open(d.path)
+	if err != nil {
+		return fmt.Errorf("failed to open GeoIP database %s: %w", d.path, err)
+	}
+	log.Printf("Using GeoIP database %s (%s, built %s)", d.path, reader.Metadata.DatabaseType,
+		time.Unix(int64(reader.Metadata.BuildEpoch), 0).UTC().Format("2006-01-02"))
+
+	if d.reader != nil {
+		d.reader.Close()
+	}
+	d.reader = reader
+	d.modTime = info.ModTime()
+	d.missing = false
+	return nil
+}
+
+// Reload открывает заново изменившиеся файлы баз. При ошибке продолжает
+// работать прежняя версия базы.
+func (d *DB) Reload() error {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var errs []error
+	for _, db := range []*database{&d.city, &d.asn} {
+		if err := db.reload(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Watch проверяет файлы баз каждые interval до отмены ctx
+func (d *DB) Watch(ctx context.Context, interval time.Duration) {
+	if d == nil || interval <= 0 || (d.city.path == "" && d.asn.path == "") {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := d.Reload(); err != nil {
+				log.Printf("GeoIP reload failed, keeping previous database: %v", err)
+			}
+		}
+	}
+}
+
+// Lookup возвращает местоположение и сеть адреса; ok=false, если адрес
+// не разобран или не найден ни в одной базе
+func (d *DB) Lookup(addr string) (Location, bool) {
+	if d == nil {
+		return Location{}, false
+	}
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return Location{}, false
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	var location Location
+	var found bool
+	if d.city.reader != nil {
+		var record cityRecord
+		if ok := lookup(d.city, ip, &record); ok {
+			found = true
+			location.Country = record.Country.ISOCode
+			location.City = record.City.Names["en"]
+			if record.Location.Latitude != nil && record.Location.Longitude != nil {
+				location.Latitude = *record.Location.Latitude
+				location.Longitude = *record.Location.Longitude
+				location.HasCoordinates = true
+			}
+		}
+	}
+	if d.asn.reader != nil {
+		var record asnRecord
+		if ok := lookup(d.asn, ip, &record); ok {
+			found = true
+			location.ASN = record.Number
+			location.Org = record.Org
+		}
+	}
+	return location, found
+}
+
+func lookup(db database, ip net.IP, record any) bool {
+	_, ok, err := db.reader.LookupNetwork(ip, record)
+	if err != nil {
+		log.Printf("GeoIP lookup of %s in %s failed: %v", ip, db.path, err)
+		return false
+	}
+	return ok
+}
+
+func (d *DB) Close() error {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var errs []error
+	for _, db := range []*database{&d.city, &d.asn} {
+		if db.reader != nil {
+			errs = append(errs, db.reader.Close())
+			db.reader = nil
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -3,46 +3,147 @@ package main
 import (
 	"alertsystem/aggregator"
 	"alertsystem/clickhouse"
+	"alertsystem/config"
 	"alertsystem/parser"
 	"alertsystem/watcher"
+	"chconfig"
 	"context"
+	"errors"
+	"expvar"
+	"flag"
+	"io/fs"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
-	//"time"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "replay":
+			runReplay(os.Args[2:])
+			return
+		case "migrate":
+			runMigrate(os.Args[2:])
+			return
+		}
+	}
+	runServe(os.Args[1:])
+}
+
+// runServe запускает непрерывное наблюдение за логами
+func runServe(args []string) {
+	flags := flag.NewFlagSet("alertsystem", flag.ExitOnError)
+	configPath := flags.String("config", "config.yaml", "path to rules configuration file (YAML or JSON)")
+	flags.Parse(args)
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Инициализация клиента ClickHouse
-	chClient, err := clickhouse.New(ctx)
+	// Счетчики ошибок и состояния доступны по /debug/vars
+	if cfg.Metrics.Listen != "" {
+		go func() {
+			mux := http.NewServeMux()
+			mux.Handle("/debug/vars", expvar.Handler())
+			log.Printf("Metrics listening on %s", cfg.Metrics.Listen)
+			if err := http.ListenAndServe(cfg.Metrics.Listen, mux); err != nil {
+				log.Printf("Metrics server error: %v", err)
+			}
+		}()
+	}
+
+	// Инициализация клиента ClickHouse. Недоступность сервера при запуске
+	// не мешает собирать алерты: они копятся в spool до его появления.
+	chConfig, err := chconfig.FromEnv()
+	if err != nil {
+		log.Fatalf("Failed to load ClickHouse config: %v", err)
+	}
+	chClient, err := clickhouse.Open(chConfig)
 	if err != nil {
 		log.Fatalf("Failed to create ClickHouse client: %v", err)
 	}
-	defer chClient.Close()
+	if err := chClient.Init(ctx); err != nil {
+		log.Printf("ClickHouse is unavailable, alerts will be spooled: %v", err)
+	}
+
+	var spool *clickhouse.Spool
+	if cfg.Spool.Dir != "" {
+		spool, err = clickhouse.OpenSpool(cfg.Spool)
+		if err != nil {
+			log.Fatalf("Failed to open spool: %v", err)
+		}
+	}
+
+	// Алерты пишутся батчами; Writer закрывает клиент при остановке агрегатора
+	writer := clickhouse.NewWriter(chClient, cfg.Writer, spool)
 
 	// Инициализация агрегатора
-	agg, err := aggregator.New(ctx, chClient)
+	agg, err := aggregator.New(ctx, writer, cfg, aggregator.Options{})
 	if err != nil {
 		log.Fatalf("Failed to create aggregator: %v", err)
 	}
-	defer agg.Close()
 
-	// Обработчик для nginx логов
-	nginxHandler := func(line string) {
-		nginxLog, err := parser.ParseNginxLine(line)
+	// Позиции чтения логов, сохраняемые между перезапусками
+	var state *watcher.StateStore
+	if cfg.Watcher.StateFile != "" {
+		state, err = watcher.OpenStateStore(cfg.Watcher.StateFile)
 		if err != nil {
-			log.Printf("Failed to parse nginx log: %v", err)
-			return
+			log.Fatalf("Failed to open watcher state: %v", err)
 		}
-		agg.ProcessLog(nginxLog)
+	}
+
+	// Адрес клиента за доверенными прокси определяется по заголовкам
+	proxies, err := parser.NewTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		log.Fatalf("Failed to load trusted proxies: %v", err)
+	}
+
+	// Каждый источник читается своим парсером
+	manager := watcher.NewManager(state, cfg.Watcher.StartFromEnd)
+	for _, source := range cfg.Sources {
+		parse, _ := parser.Lookup(source.Parser, proxies)
+		name := source.Name
+		manager.Add(watcher.Source{
+			Name:     name,
+			Patterns: source.Paths,
+			OnLine: func(line string) {
+				entry, err := parse(line)
+				if err != nil {
+					log.Printf("Failed to parse %s log: %v", name, err)
+					return
+				}
+				agg.ProcessLog(entry)
+			},
+		})
 	}
 
 	// Запуск наблюдателя
-	go watcher.New("../logs/nginx/access.log", nginxHandler).Watch()
+	watchDone := make(chan struct{})
+	go func() {
+		defer close(watchDone)
+		manager.Watch(ctx)
+	}()
+
+	// Перезагрузка правил при изменении конфигурации
+	go watcher.NewConfigWatcher(*configPath, func() {
+		newCfg, err := config.Load(*configPath)
+		if err != nil {
+			log.Printf("Config reload rejected, keeping previous config: %v", err)
+			return
+		}
+		if err := agg.Reload(newCfg); err != nil {
+			log.Printf("Config reload rejected, keeping previous config: %v", err)
+			return
+		}
+		log.Printf("Config reloaded from %s", *configPath)
+	}).Watch(ctx)
 
 	log.Println("Alert system started. Press Ctrl+C to stop.")
 
@@ -51,4 +152,20 @@ func main() {
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 	<-sigChan
 	log.Println("Shutting down...")
-}
\ No newline at end of file
+
+	// Сначала останавливаются источники записей, затем агрегатор выдает
+	// события, ожидающие пару, и последним закрывается writer с отправкой остатка
+	cancel()
+	<-watchDone
+	agg.Close()
+}
+
+// loadConfig загружает конфигурацию; при отсутствии файла используются значения по умолчанию
+func loadConfig(path string) (*config.Config, error) {
+	cfg, err := config.Load(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		log.Printf("Config file %s not found, using defaults", path)
+		return config.Default(), nil
+	}
+	return cfg, err
+}
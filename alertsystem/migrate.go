@@ -0,0 +1,60 @@
+package main
+
+import (
+	"alertsystem/clickhouse"
+	"chconfig"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+)
+
+// runMigrate применяет миграции схемы ClickHouse или показывает их состояние.
+// Сервис применяет миграции и сам при подключении; команда нужна, чтобы
+// обновить схему заранее, например перед выкладкой новой версии.
+func runMigrate(args []string) {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	status := flags.Bool("status", false, "list migrations and whether they are applied, without applying")
+	timeout := flags.Duration("timeout", 10*time.Minute, "time limit for applying migrations")
+	flags.Parse(args)
+
+	chConfig, err := chconfig.FromEnv()
+	if err != nil {
+		log.Fatalf("Failed to load ClickHouse config: %v", err)
+	}
+	chClient, err := clickhouse.Open(chConfig)
+	if err != nil {
+		log.Fatalf("Failed to create ClickHouse client: %v", err)
+	}
+	defer chClient.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	if *status {
+		statuses, err := chClient.MigrationStatuses(ctx)
+		if err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if !s.AppliedAt.IsZero() {
+				applied = "applied " + s.AppliedAt.Local().Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Printf("%04d_%s\t%s\n", s.Version, s.Name, applied)
+		}
+		return
+	}
+
+	applied, err := chClient.Migrate(ctx)
+	for _, m := range applied {
+		fmt.Printf("applied %04d_%s\n", m.Version, m.Name)
+	}
+	if err != nil {
+		log.Fatalf("migrate: %v", err)
+	}
+	if len(applied) == 0 {
+		fmt.Println("schema is up to date")
+	}
+}
@@ -0,0 +1,152 @@
+package parser
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LoginEvent - попытка входа, собранная из записи nginx и записи веб-приложения.
+// nginx знает адрес и User-Agent клиента, приложение - действительный результат аутентификации.
+type LoginEvent struct {
+	// ID - идентификатор события в login_events, на него ссылаются алерты
+	ID         string `json:"event_id"`
+	TimeLocal  string `json:"time_local"`
+	RemoteAddr string `json:"remote_addr"`
+	UserAgent  string `json:"user_agent,omitempty"`
+	Referer    string `json:"referer,omitempty"`
+	// Параметры HTTP-запроса известны только из записи nginx
+	Method        string        `json:"method,omitempty"`
+	RequestPath   string        `json:"request_path,omitempty"`
+	Query         string        `json:"query,omitempty"`
+	Protocol      string        `json:"protocol,omitempty"`
+	BodyBytesSent int64         `json:"body_bytes_sent,omitempty"`
+	RequestTime   time.Duration `json:"request_time,omitempty"`
+	ForwardedFor  string        `json:"forwarded_for,omitempty"`
+	ProxyChain    []string      `json:"proxy_chain,omitempty"` // доверенные прокси между клиентом и nginx
+	Username      string        `json:"username"`
+	Password      string        `json:"password"`
+	AuthStatus    string        `json:"auth_status"`
+	// HTTPStatus - код ответа из nginx, пустой если запись nginx не найдена
+	HTTPStatus string `json:"http_status,omitempty"`
+	// Correlated означает, что событие собрано из обоих источников.
+	// Иначе результат аутентификации взят из единственного доступного источника.
+	Correlated bool `json:"correlated"`
+	// Geo - местоположение клиента; nil, если база GeoIP не задана или адрес в ней не найден
+	Geo *Geo `json:"geo,omitempty"`
+	// ThreatFeeds - списки репутации, в которые входит адрес клиента
+	ThreatFeeds []string `json:"threat_feeds,omitempty"`
+}
+
+func (e LoginEvent) Source() string {
+	return SourceLogin
+}
+
+func (e LoginEvent) IsLogin() bool {
+	return true
+}
+
+func (e LoginEvent) GetUsername() string {
+	return e.Username
+}
+
+func (e LoginEvent) GetPassword() string {
+	return e.Password
+}
+
+func (e LoginEvent) GetRemoteAddr() string {
+	return e.RemoteAddr
+}
+
+func (e LoginEvent) GetAuthStatus() string {
+	return e.AuthStatus
+}
+
+func (e LoginEvent) GetTime() string {
+	return e.TimeLocal
+}
+
+func (e LoginEvent) GetEventID() string {
+	return e.ID
+}
+
+func (e LoginEvent) GetThreatFeeds() []string {
+	return e.ThreatFeeds
+}
+
+// NewLoginEvent объединяет записи nginx и приложения об одной попытке входа
+func NewLoginEvent(nginx NginxLog, web WebServiceLog) LoginEvent {
+	event := LoginEvent{
+		ID:            uuid.NewString(),
+		TimeLocal:     nginx.TimeLocal,
+		RemoteAddr:    nginx.RemoteAddr,
+		UserAgent:     nginx.UserAgent,
+		Referer:       nginx.Referer,
+		Method:        nginx.Method,
+		RequestPath:   nginx.Path,
+		Query:         nginx.Query,
+		Protocol:      nginx.Protocol,
+		BodyBytesSent: nginx.Bytes,
+		RequestTime:   nginx.Duration,
+		ForwardedFor:  nginx.ForwardedFor,
+		ProxyChain:    nginx.ProxyChain,
+		Username:      web.Username,
+		Password:      web.Password,
+		AuthStatus:    web.Status,
+		HTTPStatus:    nginx.Status,
+		Correlated:    true,
+		Geo:           nginx.Geo,
+		ThreatFeeds:   nginx.ThreatFeeds,
+	}
+	if event.AuthStatus == "" {
+		event.AuthStatus = nginx.GetAuthStatus()
+	}
+	if event.Geo == nil {
+		event.Geo = web.Geo
+	}
+	if event.ThreatFeeds == nil {
+		event.ThreatFeeds = web.ThreatFeeds
+	}
+	return event
+}
+
+// LoginEventFromNginx строит событие только по записи nginx,
+// результат аутентификации выводится из кода ответа
+func LoginEventFromNginx(nginx NginxLog) LoginEvent {
+	return LoginEvent{
+		ID:            uuid.NewString(),
+		TimeLocal:     nginx.TimeLocal,
+		RemoteAddr:    nginx.RemoteAddr,
+		UserAgent:     nginx.UserAgent,
+		Referer:       nginx.Referer,
+		Method:        nginx.Method,
+		RequestPath:   nginx.Path,
+		Query:         nginx.Query,
+		Protocol:      nginx.Protocol,
+		BodyBytesSent: nginx.Bytes,
+		RequestTime:   nginx.Duration,
+		ForwardedFor:  nginx.ForwardedFor,
+		ProxyChain:    nginx.ProxyChain,
+		Username:      nginx.Username,
+		Password:      nginx.Password,
+		AuthStatus:    nginx.GetAuthStatus(),
+		HTTPStatus:    nginx.Status,
+		Geo:           nginx.Geo,
+		ThreatFeeds:   nginx.ThreatFeeds,
+	}
+}
+
+// LoginEventFromWeb строит событие только по записи приложения
+func LoginEventFromWeb(web WebServiceLog) LoginEvent {
+	return LoginEvent{
+		ID:          uuid.NewString(),
+		TimeLocal:   web.TimeLocal,
+		RemoteAddr:  web.RemoteAddr,
+		ProxyChain:  web.ProxyChain,
+		Username:    web.Username,
+		Password:    web.Password,
+		AuthStatus:  web.Status,
+		Geo:         web.Geo,
+		ThreatFeeds: web.ThreatFeeds,
+	}
+}
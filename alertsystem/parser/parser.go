@@ -1,19 +1,67 @@
 package parser
 
+import "time"
+
 type Alert struct {
-	Type           string `json:"type"`
-	Date           string `json:"date"`
-	RemoteAddr     string `json:"remote_addr"`
-	Action         string `json:"action"`
-	Username       string `json:"username,omitempty"`
-	Password       string `json:"password,omitempty"`
-	AuthStatus     string `json:"auth_status"`
-	Count          int    `json:"count,omitempty"`
-	CommonPassword string `json:"common_password,omitempty"`
+	Type           string    `json:"type"`
+	Severity       string    `json:"severity,omitempty"`
+	Date           time.Time `json:"date"`
+	RemoteAddr     string    `json:"remote_addr"`
+	Action         string    `json:"action"`
+	Username       string    `json:"username,omitempty"`
+	Password       string    `json:"password,omitempty"`
+	AuthStatus     string    `json:"auth_status"`
+	Count          int       `json:"count,omitempty"`
+	CommonPassword string    `json:"common_password,omitempty"`
+	// RequestPath и Details - путь запроса и найденный признак атаки для веб-правил
+	RequestPath string `json:"request_path,omitempty"`
+	Details     string `json:"details,omitempty"`
+	// Samples - примеры значений, по которым сработало правило (например, пути при сканировании)
+	Samples []string `json:"samples,omitempty"`
+	// EventIDs - идентификаторы событий входа, по которым сработало правило
+	EventIDs []string `json:"event_ids,omitempty"`
+}
+
+// Geo - местоположение и сеть адреса клиента по офлайн-базам GeoIP
+type Geo struct {
+	Country        string  `json:"country,omitempty"`
+	City           string  `json:"city,omitempty"`
+	Latitude       float64 `json:"latitude,omitempty"`
+	Longitude      float64 `json:"longitude,omitempty"`
+	HasCoordinates bool    `json:"-"`
+	ASN            uint32  `json:"asn,omitempty"`
+	Org            string  `json:"as_org,omitempty"`
 }
 
+// Источники логов
+const (
+	SourceNginx = "nginx"
+	SourceWeb   = "web"
+	// SourceLogin - события входа, собранные из nginx и веб-приложения
+	SourceLogin = "login"
+)
+
+// Sources перечисляет все известные источники логов
+var Sources = []string{SourceNginx, SourceWeb, SourceLogin}
+
+// Результаты аутентификации
+const (
+	AuthSuccess = "success"
+	AuthFailure = "failure"
+	AuthError   = "error"
+)
+
 type LogEntry interface {
+	Source() string
 	IsLogin() bool
 	GetUsername() string
+	GetPassword() string
+	GetRemoteAddr() string
+	GetAuthStatus() string
 	GetTime() string
-}
\ No newline at end of file
+	// GetEventID возвращает идентификатор события в login_events,
+	// пустой для записей, которые не сохраняются как события
+	GetEventID() string
+	// GetThreatFeeds возвращает списки репутации, в которые входит адрес клиента
+	GetThreatFeeds() []string
+}
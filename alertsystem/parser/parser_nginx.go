@@ -3,7 +3,9 @@ package parser
 import (
 	"encoding/json"
 	"net/url"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type NginxLog struct {
@@ -11,32 +13,105 @@ type NginxLog struct {
 	RemoteAddr    string `json:"remote_addr"`
 	Request       string `json:"request"`
 	Status        string `json:"status"`
-	RequestBody   string `json:"request_body"`
-	Username      string `json:"username"`
-	Password      string `json:"password"`
+	UserAgent     string `json:"http_user_agent"`
+	Referer       string `json:"http_referer"`
+	BodyBytesSent string `json:"body_bytes_sent"`
+	RequestTime   string `json:"request_time"`
+	// ForwardedFor - заголовок X-Forwarded-For как есть, список адресов через запятую
+	ForwardedFor string `json:"http_x_forwarded_for"`
+	RealIP       string `json:"http_x_real_ip"`
+	RequestBody  string `json:"request_body"`
+	Username     string `json:"username"`
+	Password     string `json:"password"`
+
+	// Поля, разобранные из строки запроса и числовых значений nginx
+	Method   string        `json:"-"`
+	Path     string        `json:"-"`
+	Query    string        `json:"-"`
+	Protocol string        `json:"-"`
+	Bytes    int64         `json:"-"`
+	Duration time.Duration `json:"-"`
+	// ProxyChain - доверенные прокси, через которые пришел запрос, от клиента к nginx.
+	// RemoteAddr при этом - адрес клиента из заголовков, а не адрес соединения.
+	ProxyChain []string `json:"-"`
+
+	// Geo и ThreatFeeds заполняются при обогащении
+	Geo         *Geo     `json:"-"`
+	ThreatFeeds []string `json:"-"`
+}
+
+func (l NginxLog) Source() string {
+	return SourceNginx
 }
 
 func (l NginxLog) IsLogin() bool {
-	return strings.HasPrefix(l.Request, "POST") && 
-	       strings.Contains(l.Request, "/login")
+	return l.Method == "POST" && strings.Contains(l.Path, "/login")
 }
 
 func (l NginxLog) GetUsername() string {
 	return l.Username
 }
 
+func (l NginxLog) GetPassword() string {
+	return l.Password
+}
+
+func (l NginxLog) GetRemoteAddr() string {
+	return l.RemoteAddr
+}
+
+func (l NginxLog) GetThreatFeeds() []string {
+	return l.ThreatFeeds
+}
+
+// ViaProxy сообщает, пришел ли запрос от клиента через доверенный прокси с адресом addr
+func (l NginxLog) ViaProxy(addr string) bool {
+	return len(l.ProxyChain) > 0 && l.ProxyChain[len(l.ProxyChain)-1] == addr
+}
+
+// GetAuthStatus определяет результат входа по коду ответа:
+// при успехе приложение перенаправляет на /welcome (303)
+func (l NginxLog) GetAuthStatus() string {
+	switch {
+	case l.Status == "303":
+		return AuthSuccess
+	case strings.HasPrefix(l.Status, "5"):
+		return AuthError
+	default:
+		return AuthFailure
+	}
+}
+
 func (l NginxLog) GetTime() string {
 	return l.TimeLocal
 }
 
-func ParseNginxLine(line string) (NginxLog, error) {
+func (l NginxLog) GetEventID() string {
+	return ""
+}
+
+// ParseNginxLine разбирает строку лога nginx. proxies - доверенные прокси,
+// по заголовкам которых определяется адрес клиента; nil - адрес из remote_addr.
+func ParseNginxLine(line string, proxies *TrustedProxies) (NginxLog, error) {
 	var log NginxLog
 	err := json.Unmarshal([]byte(line), &log)
 	if err != nil {
 		return NginxLog{}, err
 	}
 
-	log.TimeLocal, _, _ = strings.Cut(log.TimeLocal, " ")
+	log.Method, log.Path, log.Query, log.Protocol = parseRequestLine(log.Request)
+	// "-" и пустые значения nginx дают 0
+	log.Bytes, _ = strconv.ParseInt(log.BodyBytesSent, 10, 64)
+	if seconds, err := strconv.ParseFloat(log.RequestTime, 64); err == nil {
+		log.Duration = time.Duration(seconds * float64(time.Second))
+	}
+	if log.ForwardedFor == "-" {
+		log.ForwardedFor = ""
+	}
+	if log.RealIP == "-" {
+		log.RealIP = ""
+	}
+	log.RemoteAddr, log.ProxyChain = proxies.ClientAddr(log.RemoteAddr, log.ForwardedFor, log.RealIP)
 
 	if log.IsLogin() {
 		values, err := url.ParseQuery(log.RequestBody)
@@ -46,4 +121,21 @@ func ParseNginxLine(line string) (NginxLog, error) {
 		}
 	}
 	return log, nil
-}
\ No newline at end of file
+}
+
+// parseRequestLine разбирает строку запроса "POST /login?next=/ HTTP/1.1".
+// Сканеры присылают и некорректные строки (без протокола, с пробелами в пути),
+// поэтому протоколом считается только последнее слово вида HTTP/x,
+// а все между методом и протоколом - целью запроса. Путь не декодируется.
+func parseRequestLine(request string) (method, path, query, protocol string) {
+	parts := strings.Fields(request)
+	if len(parts) == 0 {
+		return "", "", "", ""
+	}
+	method, parts = parts[0], parts[1:]
+	if n := len(parts); n > 0 && strings.HasPrefix(parts[n-1], "HTTP/") {
+		protocol, parts = parts[n-1], parts[:n-1]
+	}
+	path, query, _ = strings.Cut(strings.Join(parts, " "), "?")
+	return method, path, query, protocol
+}
@@ -0,0 +1,64 @@
+package parser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRequestLine(t *testing.T) {
+	tests := []struct {
+		request                       string
+		method, path, query, protocol string
+	}{
+		{"GET / HTTP/1.1", "GET", "/", "", "HTTP/1.1"},
+		{"POST /login?next=/admin HTTP/1.1", "POST", "/login", "next=/admin", "HTTP/1.1"},
+		{"GET /a?b=1?c=2 HTTP/2.0", "GET", "/a", "b=1?c=2", "HTTP/2.0"},
+		{"GET /%2e%2e/etc/passwd HTTP/1.0", "GET", "/%2e%2e/etc/passwd", "", "HTTP/1.0"},
+		// Сканеры присылают строки без протокола и с пробелами в пути
+		{"GET /", "GET", "/", "", ""},
+		{"GET /cgi-bin/() { :; } HTTP/1.1", "GET", "/cgi-bin/() { :; }", "", "HTTP/1.1"},
+		{"GET", "GET", "", "", ""},
+		{"\\x16\\x03\\x01", "\\x16\\x03\\x01", "", "", ""},
+		{"", "", "", "", ""},
+	}
+	for _, tt := range tests {
+		method, path, query, protocol := parseRequestLine(tt.request)
+		if method != tt.method || path != tt.path || query != tt.query || protocol != tt.protocol {
+			t.Errorf("parseRequestLine(%q) = %q, %q, %q, %q; want %q, %q, %q, %q",
+				tt.request, method, path, query, protocol, tt.method, tt.path, tt.query, tt.protocol)
+		}
+	}
+}
+
+func TestParseNginxLine(t *testing.T) {
+	line := `{"time_local":"18/Oct/2026:10:00:00 +0300","remote_addr":"192.0.2.1",` +
+		`"request":"POST /login HTTP/1.1","status":"303","body_bytes_sent":"-",` +
+		`"http_x_forwarded_for":"-","http_x_real_ip":"-","request_time":"0.250",` +
+		`"request_body":"username=admin&password=p%40ss"}`
+	log, err := ParseNginxLine(line, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if log.Method != "POST" || log.Path != "/login" || log.Protocol != "HTTP/1.1" {
+		t.Errorf("request = %q %q %q", log.Method, log.Path, log.Protocol)
+	}
+	if log.Bytes != 0 || log.Duration != 250*time.Millisecond {
+		t.Errorf("Bytes = %d, Duration = %s", log.Bytes, log.Duration)
+	}
+	if log.ForwardedFor != "" || log.RealIP != "" {
+		t.Errorf("\"-\" headers were not cleared: %q, %q", log.ForwardedFor, log.RealIP)
+	}
+	if !log.IsLogin() || log.Username != "admin" || log.Password != "p@ss" || log.GetAuthStatus() != AuthSuccess {
+		t.Errorf("login = %v %q %q %q", log.IsLogin(), log.Username, log.Password, log.GetAuthStatus())
+	}
+
+	// Тело запроса разбирается только у попыток входа
+	log, err = ParseNginxLine(`{"request":"POST /upload HTTP/1.1","request_body":"username=admin"}`, nil)
+	if err != nil || log.IsLogin() || log.Username != "" {
+		t.Errorf("non-login request: IsLogin %v, Username %q, err %v", log.IsLogin(), log.Username, err)
+	}
+
+	if _, err := ParseNginxLine("not json", nil); err == nil {
+		t.Error("ParseNginxLine accepted a malformed line")
+	}
+}
@@ -0,0 +1,131 @@
+package parser
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// TrustedProxies - сети балансировщиков и CDN перед nginx. Только им
+// доверяются заголовки X-Forwarded-For и X-Real-IP: от остальных адресов
+// заголовок может прислать сам клиент, подставив любой адрес.
+type TrustedProxies struct {
+	prefixes []netip.Prefix
+}
+
+// NewTrustedProxies разбирает список сетей CIDR или отдельных адресов.
+// Пустой список - доверенных прокси нет, адрес клиента берется из remote_addr.
+func NewTrustedProxies(networks []string) (*TrustedProxies, error) {
+	if len(networks) == 0 {
+		return nil, nil
+	}
+	p := &TrustedProxies{}
+	for _, network := range networks {
+		prefix, err := parsePrefix(network)
+		if err != nil {
+			return nil, err
+		}
+		p.prefixes = append(p.prefixes, prefix)
+	}
+	return p, nil
+}
+
+func parsePrefix(network string) (netip.Prefix, error) {
+	if !strings.Contains(network, "/") {
+		addr, err := netip.ParseAddr(network)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("bad trusted proxy %q: must be an address or CIDR", network)
+		}
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+	prefix, err := netip.ParsePrefix(network)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("bad trusted proxy %q: must be an address or CIDR", network)
+	}
+	// Сети вида ::ffff:0:0/96 приводятся к IPv4, как и проверяемые адреса
+	if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+		prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+	}
+	return prefix.Masked(), nil
+}
+
+func (p *TrustedProxies) contains(addr netip.Addr) bool {
+	for _, prefix := range p.prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientAddr определяет адрес клиента по адресу соединения и заголовкам.
+// Если соединение пришло от доверенного прокси, X-Forwarded-For просматривается
+// справа налево до первого недоверенного адреса; X-Real-IP используется,
+// когда X-Forwarded-For нет. chain - пройденные доверенные прокси от клиента
+// к серверу (последний - remoteAddr), nil при прямом подключении.
+func (p *TrustedProxies) ClientAddr(remoteAddr, forwardedFor, realIP string) (client string, chain []string) {
+	if p == nil {
+		return remoteAddr, nil
+	}
+	peer, ok := parseHop(remoteAddr)
+	if !ok || !p.contains(peer) {
+		return remoteAddr, nil
+	}
+
+	trusted := []netip.Addr{peer}
+	hops := strings.Split(forwardedFor, ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		addr, ok := parseHop(hop)
+		if !ok {
+			// "unknown" и обфусцированные имена (RFC 7239): дальше цепочке не верим,
+			// клиентом считается ближайший к нему доверенный прокси
+			break
+		}
+		// nginx добавляет в X-Forwarded-For приложения тот же адрес,
+		// что передает ему в X-Real-IP
+		if addr == trusted[0] {
+			continue
+		}
+		if !p.contains(addr) {
+			return addr.String(), addrStrings(trusted)
+		}
+		trusted = append([]netip.Addr{addr}, trusted...)
+	}
+
+	if strings.TrimSpace(forwardedFor) == "" {
+		if addr, ok := parseHop(strings.TrimSpace(realIP)); ok && addr != peer {
+			return addr.String(), addrStrings(trusted)
+		}
+	}
+	// Все адреса цепочки доверенные: клиент - самый дальний из них
+	if len(trusted) == 1 {
+		return remoteAddr, nil
+	}
+	return trusted[0].String(), addrStrings(trusted[1:])
+}
+
+// parseHop разбирает адрес из заголовка; допускается адрес с портом
+func parseHop(hop string) (netip.Addr, bool) {
+	addr, err := netip.ParseAddr(hop)
+	if err != nil {
+		addrPort, err := netip.ParseAddrPort(hop)
+		if err != nil {
+			return netip.Addr{}, false
+		}
+		addr = addrPort.Addr()
+	}
+	return addr.Unmap().WithZone(""), true
+}
+
+func addrStrings(addrs []netip.Addr) []string {
+	result := make([]string, len(addrs))
+	for i, addr := range addrs {
+		result[i] = addr.String()
+	}
+	return result
+}
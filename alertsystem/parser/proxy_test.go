@@ -0,0 +1,94 @@
+package parser
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestClientAddr(t *testing.T) {
+	proxies, err := NewTrustedProxies([]string{"10.0.0.0/8", "203.0.113.5", "::ffff:192.0.2.0/120", "2001:db8::/32"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		realIP       string
+		client       string
+		chain        []string
+	}{
+		{"untrusted peer, headers ignored", "198.51.100.1", "6.6.6.6", "7.7.7.7", "198.51.100.1", nil},
+		{"trusted peer without headers", "10.0.0.1", "", "", "10.0.0.1", nil},
+		{"single hop", "10.0.0.1", "6.6.6.6", "", "6.6.6.6", []string{"10.0.0.1"}},
+		{"spoofed left part is skipped", "10.0.0.1", "9.9.9.9, 6.6.6.6, 203.0.113.5", "", "6.6.6.6", []string{"203.0.113.5", "10.0.0.1"}},
+		{"all hops trusted", "10.0.0.1", "10.1.1.1, 10.2.2.2", "", "10.1.1.1", []string{"10.2.2.2", "10.0.0.1"}},
+		{"X-Real-IP without X-Forwarded-For", "10.0.0.1", "", "6.6.6.6", "6.6.6.6", []string{"10.0.0.1"}},
+		{"X-Forwarded-For wins over X-Real-IP", "10.0.0.1", "6.6.6.6", "7.7.7.7", "6.6.6.6", []string{"10.0.0.1"}},
+		{"unparsable X-Real-IP", "10.0.0.1", "", "unknown", "10.0.0.1", nil},
+		{"unknown hop stops the walk", "10.0.0.1", "6.6.6.6, unknown, 10.2.2.2", "", "10.2.2.2", []string{"10.0.0.1"}},
+		{"nginx repeats its peer for the app", "10.0.0.1", "6.6.6.6, 10.0.0.1", "", "6.6.6.6", []string{"10.0.0.1"}},
+		{"empty hops", "10.0.0.1", " , 6.6.6.6,", "", "6.6.6.6", []string{"10.0.0.1"}},
+		{"address with port", "10.0.0.1", "[2001:db9::1]:443, 6.6.6.6:1234", "", "6.6.6.6", []string{"10.0.0.1"}},
+		{"IPv4-mapped proxy network", "192.0.2.7", "2001:db9::1", "", "2001:db9::1", []string{"192.0.2.7"}},
+		{"IPv4-mapped client", "10.0.0.1", "::ffff:6.6.6.6", "", "6.6.6.6", []string{"10.0.0.1"}},
+		{"IPv6 proxy", "2001:db8::10", "6.6.6.6", "", "6.6.6.6", []string{"2001:db8::10"}},
+		{"malformed remote_addr", "-", "6.6.6.6", "", "-", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, chain := proxies.ClientAddr(tt.remoteAddr, tt.forwardedFor, tt.realIP)
+			if client != tt.client || !slices.Equal(chain, tt.chain) {
+				t.Errorf("ClientAddr(%q, %q, %q) = %q, %q; want %q, %q",
+					tt.remoteAddr, tt.forwardedFor, tt.realIP, client, chain, tt.client, tt.chain)
+			}
+		})
+	}
+}
+
+func TestNoTrustedProxies(t *testing.T) {
+	proxies, err := NewTrustedProxies(nil)
+	if err != nil || proxies != nil {
+		t.Fatalf("NewTrustedProxies(nil) = %v, %v", proxies, err)
+	}
+	if client, chain := proxies.ClientAddr("10.0.0.1", "6.6.6.6", "7.7.7.7"); client != "10.0.0.1" || chain != nil {
+		t.Errorf("ClientAddr without proxies = %q, %q", client, chain)
+	}
+}
+
+func TestNewTrustedProxiesErrors(t *testing.T) {
+	for _, network := range []string{"bogus", "10.0.0.0/33", "10.0.0.0/8/8", ""} {
+		if _, err := NewTrustedProxies([]string{network}); err == nil {
+			t.Errorf("NewTrustedProxies(%q) succeeded", network)
+		}
+	}
+}
+
+func TestParseWithTrustedProxies(t *testing.T) {
+	proxies, err := NewTrustedProxies([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nginx, err := ParseNginxLine(`{"remote_addr":"10.0.0.1","request":"GET / HTTP/1.1","http_x_forwarded_for":"-","http_x_real_ip":"6.6.6.6"}`, proxies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nginx.RemoteAddr != "6.6.6.6" || !nginx.ViaProxy("10.0.0.1") || nginx.ViaProxy("6.6.6.6") {
+		t.Errorf("nginx: RemoteAddr %q, ProxyChain %q", nginx.RemoteAddr, nginx.ProxyChain)
+	}
+
+	web, err := ParseWebServiceLine(`{"remote_addr":"10.0.0.1","forwarded_for":"6.6.6.6, 10.0.0.1","username":"admin","status":"failure"}`, proxies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if web.RemoteAddr != "6.6.6.6" || !slices.Equal(web.ProxyChain, []string{"10.0.0.1"}) {
+		t.Errorf("web: RemoteAddr %q, ProxyChain %q", web.RemoteAddr, web.ProxyChain)
+	}
+
+	event := NewLoginEvent(nginx, web)
+	if !slices.Equal(event.ProxyChain, []string{"10.0.0.1"}) {
+		t.Errorf("login event ProxyChain = %q", event.ProxyChain)
+	}
+}
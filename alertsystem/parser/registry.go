@@ -0,0 +1,38 @@
+package parser
+
+import "sort"
+
+// ParseFunc разбирает одну строку лога
+type ParseFunc func(line string) (LogEntry, error)
+
+// Парсеры, доступные для привязки к источникам логов в конфигурации
+var parsers = map[string]func(line string, proxies *TrustedProxies) (LogEntry, error){
+	SourceNginx: func(line string, proxies *TrustedProxies) (LogEntry, error) {
+		return ParseNginxLine(line, proxies)
+	},
+	SourceWeb: func(line string, proxies *TrustedProxies) (LogEntry, error) {
+		return ParseWebServiceLine(line, proxies)
+	},
+}
+
+// Lookup возвращает парсер по имени. proxies - доверенные прокси для
+// определения адреса клиента, nil - адрес берется из записи как есть.
+func Lookup(name string, proxies *TrustedProxies) (ParseFunc, bool) {
+	parse, ok := parsers[name]
+	if !ok {
+		return nil, false
+	}
+	return func(line string) (LogEntry, error) {
+		return parse(line, proxies)
+	}, true
+}
+
+// Parsers возвращает имена всех доступных парсеров
+func Parsers() []string {
+	names := make([]string, 0, len(parsers))
+	for name := range parsers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
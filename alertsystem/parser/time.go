@@ -0,0 +1,43 @@
+package parser
+
+import (
+	"expvar"
+	"fmt"
+	"time"
+)
+
+// timeLayouts - поддерживаемые форматы времени в порядке проверки.
+// Форматы со смещением дают точный момент; для форматов без смещения
+// (старые записи веб-приложения) используется локальный часовой пояс.
+var timeLayouts = []struct {
+	layout    string
+	hasOffset bool
+}{
+	{"02/Jan/2006:15:04:05 -0700", true}, // nginx $time_local, веб-приложение
+	{time.RFC3339Nano, true},             // ISO 8601, nginx $time_iso8601
+	{"2006-01-02 15:04:05.999999999Z07:00", true},
+	{"2006-01-02T15:04:05.999999999", false},
+	{"2006-01-02 15:04:05.999999999", false},
+	{"02/Jan/2006:15:04:05", false},
+}
+
+// TimeParseErrors - количество записей, время которых не удалось разобрать
+var TimeParseErrors = expvar.NewInt("parser_time_parse_errors")
+
+// ParseTime разбирает время записи лога и возвращает его в UTC
+func ParseTime(value string) (time.Time, error) {
+	for _, l := range timeLayouts {
+		var t time.Time
+		var err error
+		if l.hasOffset {
+			t, err = time.Parse(l.layout, value)
+		} else {
+			t, err = time.ParseInLocation(l.layout, value, time.Local)
+		}
+		if err == nil {
+			return t.UTC(), nil
+		}
+	}
+	TimeParseErrors.Add(1)
+	return time.Time{}, fmt.Errorf("unsupported time format %q", value)
+}
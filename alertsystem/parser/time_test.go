@@ -0,0 +1,49 @@
+package parser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTime(t *testing.T) {
+	// Форматы без смещения разбираются в локальном часовом поясе
+	local := time.Local
+	time.Local = time.FixedZone("MSK", 3*60*60)
+	defer func() { time.Local = local }()
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"18/Oct/2026:10:00:00 +0300", "2026-10-18T07:00:00Z"},
+		{"18/Oct/2026:07:00:00 +0000", "2026-10-18T07:00:00Z"},
+		{"2026-10-18T10:00:00+03:00", "2026-10-18T07:00:00Z"},
+		{"2026-10-18T07:00:00.123Z", "2026-10-18T07:00:00.123Z"},
+		{"2026-10-18 10:00:00.5+03:00", "2026-10-18T07:00:00.5Z"},
+		{"2026-10-18T10:00:00", "2026-10-18T07:00:00Z"},
+		{"2026-10-18 10:00:00.250", "2026-10-18T07:00:00.25Z"},
+		{"18/Oct/2026:10:00:00", "2026-10-18T07:00:00Z"},
+	}
+	for _, tt := range tests {
+		got, err := ParseTime(tt.value)
+		if err != nil {
+			t.Errorf("ParseTime(%q) error: %v", tt.value, err)
+			continue
+		}
+		if got.Location() != time.UTC || got.Format(time.RFC3339Nano) != tt.want {
+			t.Errorf("ParseTime(%q) = %s, want %s", tt.value, got.Format(time.RFC3339Nano), tt.want)
+		}
+	}
+}
+
+func TestParseTimeErrors(t *testing.T) {
+	before := TimeParseErrors.Value()
+	for _, value := range []string{"", "-", "yesterday", "18/Oct/2026", "2026-13-01T00:00:00Z"} {
+		if got, err := ParseTime(value); err == nil {
+			t.Errorf("ParseTime(%q) = %s, want error", value, got)
+		}
+	}
+	if errors := TimeParseErrors.Value() - before; errors != 5 {
+		t.Errorf("parser_time_parse_errors grew by %d, want 5", errors)
+	}
+}
@@ -5,30 +5,73 @@ import (
 )
 
 type WebServiceLog struct {
-	TimeLocal string `json:"time_local"`
-	Level     string `json:"level"`
-	Status    string `json:"status"`
-	Username  string `json:"username"`
-	Password  string `json:"password"`
+	TimeLocal  string `json:"time_local"`
+	RemoteAddr string `json:"remote_addr"`
+	Level      string `json:"level"`
+	Status     string `json:"status"`
+	Username   string `json:"username"`
+	Password   string `json:"password"`
+	// ForwardedFor - заголовок X-Forwarded-For, полученный приложением от nginx
+	ForwardedFor string `json:"forwarded_for"`
+	// ProxyChain - доверенные прокси, через которые пришел запрос, от клиента к nginx
+	ProxyChain []string `json:"-"`
+	// Geo и ThreatFeeds заполняются при обогащении
+	Geo         *Geo     `json:"-"`
+	ThreatFeeds []string `json:"-"`
 }
 
+func (l WebServiceLog) Source() string {
+	return SourceWeb
+}
+
+// IsLogin отличает записи о попытках входа от прочих сообщений приложения
+// (запуск, ошибки): у попытки есть имя пользователя и известный результат
 func (l WebServiceLog) IsLogin() bool {
-	return true // Все записи в этом логе считаем попытками входа
+	switch l.Status {
+	case AuthSuccess, AuthFailure, AuthError:
+		return l.Username != ""
+	}
+	return false
 }
 
 func (l WebServiceLog) GetUsername() string {
 	return l.Username
 }
 
+func (l WebServiceLog) GetPassword() string {
+	return l.Password
+}
+
+func (l WebServiceLog) GetRemoteAddr() string {
+	return l.RemoteAddr
+}
+
+func (l WebServiceLog) GetThreatFeeds() []string {
+	return l.ThreatFeeds
+}
+
+// GetAuthStatus возвращает результат входа, записанный самим приложением
+func (l WebServiceLog) GetAuthStatus() string {
+	return l.Status
+}
+
 func (l WebServiceLog) GetTime() string {
 	return l.TimeLocal
 }
 
-func ParseWebServiceLine(line string) (WebServiceLog, error) {
+func (l WebServiceLog) GetEventID() string {
+	return ""
+}
+
+// ParseWebServiceLine разбирает строку лога приложения. remote_addr в нем -
+// адрес соединения с nginx (X-Real-IP), адрес клиента за доверенными прокси
+// определяется так же, как для nginx.
+func ParseWebServiceLine(line string, proxies *TrustedProxies) (WebServiceLog, error) {
 	var log WebServiceLog
 	err := json.Unmarshal([]byte(line), &log)
 	if err != nil {
 		return WebServiceLog{}, err
 	}
+	log.RemoteAddr, log.ProxyChain = proxies.ClientAddr(log.RemoteAddr, log.ForwardedFor, "")
 	return log, nil
 }
\ No newline at end of file
@@ -0,0 +1,27 @@
+package parser
+
+import "testing"
+
+func TestWebServiceLogIsLogin(t *testing.T) {
+	tests := []struct {
+		line  string
+		login bool
+	}{
+		{`{"remote_addr":"192.0.2.1","status":"success","username":"admin","password":"x"}`, true},
+		{`{"remote_addr":"192.0.2.1","status":"failure","username":"admin","password":""}`, true},
+		{`{"remote_addr":"192.0.2.1","status":"error","username":"' OR 1=1 --"}`, true},
+		// Сообщения uvicorn, запуска и трассировки ошибок
+		{`{"time_local":"18/Oct/2026:10:00:00 +0300","level":"INFO"}`, false},
+		{`{"level":"ERROR","status":"error"}`, false},
+		{`{"status":"started","username":"admin"}`, false},
+	}
+	for _, tt := range tests {
+		log, err := ParseWebServiceLine(tt.line, nil)
+		if err != nil {
+			t.Fatalf("ParseWebServiceLine(%s): %v", tt.line, err)
+		}
+		if got := log.IsLogin(); got != tt.login {
+			t.Errorf("IsLogin(%s) = %v, want %v", tt.line, got, tt.login)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package rdns
+
+import (
+	"container/list"
+	"time"
+)
+
+type cacheEntry struct {
+	addr     string
+	hostname string // пустое имя - у адреса нет PTR-записи или запрос не удался
+	expires  time.Time
+}
+
+// cache - LRU-кэш результатов с временем жизни записей.
+// Не потокобезопасен, доступ защищает Resolver.
+type cache struct {
+	size  int
+	items map[string]*list.Element
+	order *list.List // от недавно использованных к давно использованным
+}
+
+func newCache(size int) *cache {
+	return &cache{
+		size:  size,
+		items: make(map[string]*list.Element),
+		order: list.New(),
+	}
+}
+
+// get возвращает имя из кэша; ok=false, если записи нет или она устарела
+func (c *cache) get(addr string, now time.Time) (string, bool) {
+	elem, ok := c.items[addr]
+	if !ok {
+		return "", false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if now.After(entry.expires) {
+		c.order.Remove(elem)
+		delete(c.items, addr)
+		return "", false
+	}
+	c.order.MoveToFront(elem)
+	return entry.hostname, true
+}
+
+// add сохраняет результат, вытесняя давно не использованные записи
+func (c *cache) add(addr, hostname string, expires time.Time) {
+	if elem, ok := c.items[addr]; ok {
+		entry := elem.Value.(*cacheEntry)
+		entry.hostname = hostname
+		entry.expires = expires
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.items[addr] = c.order.PushFront(&cacheEntry{addr: addr, hostname: hostname, expires: expires})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).addr)
+	}
+}
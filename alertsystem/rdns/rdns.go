@@ -0,0 +1,207 @@
+// Package rdns определяет имена хостов клиентов по PTR-записям.
+// Запросы выполняются в фоне: Lookup никогда не ждет DNS, а возвращает
+// результат из кэша и ставит неизвестный адрес в очередь на разрешение.
+package rdns
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Результаты запросов: resolved, not_found, failed и dropped (очередь заполнена)
+var lookups = expvar.NewMap("rdns_lookups")
+
+// Config - параметры обратного разрешения имен
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+	// Server - DNS-сервер host:port; пустое значение - системный резолвер
+	Server  string        `yaml:"server"`
+	Timeout time.Duration `yaml:"timeout"`
+	// CacheSize - сколько адресов хранить в кэше
+	CacheSize int           `yaml:"cache_size"`
+	TTL       time.Duration `yaml:"ttl"`
+	// NegativeTTL - сколько помнить адреса без PTR-записи и неудачные запросы
+	NegativeTTL time.Duration `yaml:"negative_ttl"`
+	Workers     int           `yaml:"workers"`
+	// QueueSize - сколько адресов может ждать разрешения; лишние пропускаются
+	QueueSize int `yaml:"queue_size"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Timeout:     2 * time.Second,
+		CacheSize:   10000,
+		TTL:         1 * time.Hour,
+		NegativeTTL: 10 * time.Minute,
+		Workers:     4,
+		QueueSize:   1000,
+	}
+}
+
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	var errs []error
+	if c.Server != "" {
+		if _, _, err := net.SplitHostPort(c.Server); err != nil {
+			errs = append(errs, fmt.Errorf("server must be host:port, got %q", c.Server))
+		}
+	}
+	if c.Timeout <= 0 {
+		errs = append(errs, fmt.Errorf("timeout must be positive, got %s", c.Timeout))
+	}
+	if c.CacheSize <= 0 {
+		errs = append(errs, fmt.Errorf("cache_size must be positive, got %d", c.CacheSize))
+	}
+	if c.TTL <= 0 {
+		errs = append(errs, fmt.Errorf("ttl must be positive, got %s", c.TTL))
+	}
+	if c.NegativeTTL <= 0 {
+		errs = append(errs, fmt.Errorf("negative_ttl must be positive, got %s", c.NegativeTTL))
+	}
+	if c.Workers <= 0 {
+		errs = append(errs, fmt.Errorf("workers must be positive, got %d", c.Workers))
+	}
+	if c.QueueSize <= 0 {
+		errs = append(errs, fmt.Errorf("queue_size must be positive, got %d", c.QueueSize))
+	}
+	return errors.Join(errs...)
+}
+
+type Resolver struct {
+	cfg Config
+	// lookupAddr выполняет PTR-запрос; в тестах подменяется
+	lookupAddr func(ctx context.Context, addr string) ([]string, error)
+	queue      chan string
+	ctx        context.Context
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
+
+	mu      sync.Mutex
+	cache   *cache
+	pending map[string]bool // адреса в очереди или в работе
+}
+
+// New запускает фоновые запросы. Выключенный резолвер ничего не разрешает.
+func New(cfg Config) *Resolver {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	resolver := &net.Resolver{PreferGo: true}
+	if cfg.Server != "" {
+		server := cfg.Server
+		resolver.Dial = func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, server)
+		}
+	}
+
+	return start(cfg, resolver.LookupAddr)
+}
+
+// start запускает обработчики очереди с заданной функцией запроса
+func start(cfg Config, lookupAddr func(ctx context.Context, addr string) ([]string, error)) *Resolver {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &Resolver{
+		cfg:        cfg,
+		lookupAddr: lookupAddr,
+		queue:      make(chan string, cfg.QueueSize),
+		ctx:        ctx,
+		cancel:     cancel,
+		cache:      newCache(cfg.CacheSize),
+		pending:    make(map[string]bool),
+	}
+	for range cfg.Workers {
+		r.wg.Add(1)
+		go r.worker()
+	}
+	return r
+}
+
+// Lookup возвращает имя хоста из кэша. Если адрес еще не разрешался
+// или результат устарел, запрос ставится в очередь, а ok=false.
+func (r *Resolver) Lookup(remoteAddr string) (string, bool) {
+	if r == nil {
+		return "", false
+	}
+	addr, err := netip.ParseAddr(remoteAddr)
+	if err != nil {
+		return "", false
+	}
+	key := addr.Unmap().WithZone("").String()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if hostname, ok := r.cache.get(key, time.Now()); ok {
+		return hostname, true
+	}
+	if r.pending[key] || r.ctx.Err() != nil {
+		return "", false
+	}
+	select {
+	case r.queue <- key:
+		r.pending[key] = true
+	default:
+		lookups.Add("dropped", 1)
+	}
+	return "", false
+}
+
+func (r *Resolver) worker() {
+	defer r.wg.Done()
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case addr := <-r.queue:
+			r.resolve(addr)
+		}
+	}
+}
+
+func (r *Resolver) resolve(addr string) {
+	ctx, cancel := context.WithTimeout(r.ctx, r.cfg.Timeout)
+	names, err := r.lookupAddr(ctx, addr)
+	cancel()
+
+	var hostname string
+	ttl := r.cfg.NegativeTTL
+	var dnsErr *net.DNSError
+	switch {
+	case err == nil && len(names) > 0:
+		hostname = strings.TrimSuffix(names[0], ".")
+		ttl = r.cfg.TTL
+		lookups.Add("resolved", 1)
+	case err == nil, errors.As(err, &dnsErr) && dnsErr.IsNotFound:
+		lookups.Add("not_found", 1)
+	default:
+		lookups.Add("failed", 1)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.pending, addr)
+	// При остановке запросы прерываются, их результат не сохраняется
+	if r.ctx.Err() == nil {
+		r.cache.add(addr, hostname, time.Now().Add(ttl))
+	}
+}
+
+// Close останавливает фоновые запросы
+func (r *Resolver) Close() {
+	if r == nil {
+		return
+	}
+	r.cancel()
+	r.wg.Wait()
+}
@@ -0,0 +1,347 @@
+package rdns
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDNS - подмена PTR-запросов с подсчетом обращений
+type fakeDNS struct {
+	mu      sync.Mutex
+	calls   map[string]int
+	answers map[string][]string
+	errs    map[string]error
+}
+
+func (f *fakeDNS) lookupAddr(ctx context.Context, addr string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.calls == nil {
+		f.calls = make(map[string]int)
+	}
+	f.calls[addr]++
+	if err := f.errs[addr]; err != nil {
+		return nil, err
+	}
+	return f.answers[addr], nil
+}
+
+func (f *fakeDNS) count(addr string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[addr]
+}
+
+func testConfig() Config {
+	cfg := DefaultConfig()
+	cfg.Enabled = true
+	return cfg
+}
+
+// waitLookup повторяет Lookup, пока результат не появится в кэше
+func waitLookup(t *testing.T, r *Resolver, addr string) string {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if hostname, ok := r.Lookup(addr); ok {
+			return hostname
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("Lookup(%q) was not resolved in time", addr)
+	return ""
+}
+
+func TestLookup(t *testing.T) {
+	dns := &fakeDNS{
+		answers: map[string][]string{
+			"192.0.2.1":   {"scanner.example.com.", "other.example.com."},
+			"2001:db8::1": {"v6.example.com."},
+		},
+		errs: map[string]error{
+			"192.0.2.2": &net.DNSError{Err: "no such host", IsNotFound: true},
+			"192.0.2.3": &net.DNSError{Err: "i/o timeout", IsTimeout: true},
+		},
+	}
+	r := start(testConfig(), dns.lookupAddr)
+	defer r.Close()
+
+	tests := []struct {
+		name     string
+		addr     string
+		key      string
+		hostname string
+	}{
+		{"first name without trailing dot", "192.0.2.1", "192.0.2.1", "scanner.example.com"},
+		{"IPv4-mapped address", "::ffff:192.0.2.1", "192.0.2.1", "scanner.example.com"},
+		{"IPv6", "2001:db8::1", "2001:db8::1", "v6.example.com"},
+		{"no PTR record", "192.0.2.2", "192.0.2.2", ""},
+		{"failed query", "192.0.2.3", "192.0.2.3", ""},
+		{"empty answer", "192.0.2.4", "192.0.2.4", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := waitLookup(t, r, tt.addr); got != tt.hostname {
+				t.Errorf("Lookup(%q) = %q, want %q", tt.addr, got, tt.hostname)
+			}
+			// Повторный вызов отвечает из кэша, без нового запроса
+			r.Lookup(tt.addr)
+			if n := dns.count(tt.key); n != 1 {
+				t.Errorf("lookups of %s = %d, want 1", tt.key, n)
+			}
+		})
+	}
+
+	if _, ok := r.Lookup("not an address"); ok {
+		t.Error("Lookup of a malformed address succeeded")
+	}
+}
+
+func TestNegativeTTL(t *testing.T) {
+	dns := &fakeDNS{errs: map[string]error{"192.0.2.1": errors.New("server misbehaving")}}
+	cfg := testConfig()
+	cfg.NegativeTTL = time.Nanosecond
+	r := start(cfg, dns.lookupAddr)
+	defer r.Close()
+
+	// Неудачный результат сразу устаревает, и адрес запрашивается снова
+	deadline := time.Now().Add(time.Second)
+	for dns.count("192.0.2.1") < 2 && time.Now().Before(deadline) {
+		r.Lookup("192.0.2.1")
+		time.Sleep(time.Millisecond)
+	}
+	if n := dns.count("192.0.2.1"); n < 2 {
+		t.Errorf("lookups after negative TTL = %d, want at least 2", n)
+	}
+}
+
+func TestQueueFull(t *testing.T) {
+	started := make(chan string, 1)
+	release := make(chan struct{})
+	cfg := testConfig()
+	cfg.Workers = 1
+	cfg.QueueSize = 1
+	r := start(cfg, func(ctx context.Context, addr string) ([]string, error) {
+		started <- addr
+		<-release
+		return []string{addr + ".example.com."}, nil
+	})
+	defer r.Close()
+
+	droppedBefore := droppedCount()
+
+	// Первый адрес занимает обработчик, второй ждет в очереди, третий не помещается
+	r.Lookup("192.0.2.1")
+	<-started
+	r.Lookup("192.0.2.2")
+	r.Lookup("192.0.2.3")
+	// Адрес в очереди не ставится в нее повторно
+	r.Lookup("192.0.2.2")
+
+	if dropped := droppedCount() - droppedBefore; dropped != 1 {
+		t.Errorf("dropped = %d, want 1", dropped)
+	}
+
+	close(release)
+	<-started
+	if got := waitLookup(t, r, "192.0.2.2"); got != "192.0.2.2.example.com" {
+		t.Errorf("Lookup(192.0.2.2) = %q", got)
+	}
+	// Пропущенный адрес ставится в очередь при следующем обращении
+	go func() { <-started }()
+	if got := waitLookup(t, r, "192.0.2.3"); got != "192.0.2.3.example.com" {
+		t.Errorf("Lookup(192.0.2.3) = %q", got)
+	}
+}
+
+func droppedCount() int64 {
+	if dropped, ok := lookups.Get("dropped").(*expvar.Int); ok {
+		return dropped.Value()
+	}
+	return 0
+}
+
+func TestCache(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := newCache(2)
+	c.add("a", "a.example.com", now.Add(time.Minute))
+	c.add("b", "", now.Add(time.Minute))
+	// a становится недавно использованным, вытесняется b
+	if hostname, ok := c.get("a", now); !ok || hostname != "a.example.com" {
+		t.Fatalf("get(a) = %q, %v", hostname, ok)
+	}
+	c.add("c", "c.example.com", now.Add(time.Second))
+
+	tests := []struct {
+		addr     string
+		at       time.Time
+		hostname string
+		ok       bool
+	}{
+		{"b", now, "", false},
+		{"a", now, "a.example.com", true},
+		{"c", now, "c.example.com", true},
+		{"c", now.Add(2 * time.Second), "", false},
+		{"c", now, "", false}, // устаревшая запись удалена
+	}
+	for _, tt := range tests {
+		hostname, ok := c.get(tt.addr, tt.at)
+		if hostname != tt.hostname || ok != tt.ok {
+			t.Errorf("get(%q, %s) = %q, %v; want %q, %v", tt.addr, tt.at.Format(time.TimeOnly), hostname, ok, tt.hostname, tt.ok)
+		}
+	}
+	if c.order.Len() != len(c.items) || len(c.items) != 1 {
+		t.Errorf("cache holds %d items, list %d; want 1", len(c.items), c.order.Len())
+	}
+}
+
+// stubDNS - DNS-сервер на 127.0.0.1, отвечающий на PTR-запросы по таблице;
+// на остальные имена отвечает NXDOMAIN
+type stubDNS struct {
+	conn    net.PacketConn
+	answers map[string]string // имя запроса -> имя хоста
+	mu      sync.Mutex
+	queries []string
+}
+
+func newStubDNS(t *testing.T, answers map[string]string) *stubDNS {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &stubDNS{conn: conn, answers: answers}
+	t.Cleanup(func() { conn.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *stubDNS) addr() string {
+	return s.conn.LocalAddr().String()
+}
+
+func (s *stubDNS) serve() {
+	buf := make([]byte, 1500)
+	for {
+		n, peer, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		if reply := s.reply(buf[:n]); reply != nil {
+			s.conn.WriteTo(reply, peer)
+		}
+	}
+}
+
+func (s *stubDNS) reply(query []byte) []byte {
+	const headerLen = 12
+	if len(query) < headerLen {
+		return nil
+	}
+	name, end, ok := readName(query, headerLen)
+	if !ok || end+4 > len(query) {
+		return nil
+	}
+	qtype := int(query[end])<<8 | int(query[end+1])
+	question := query[headerLen : end+4]
+
+	s.mu.Lock()
+	s.queries = append(s.queries, name)
+	s.mu.Unlock()
+
+	hostname, found := s.answers[name]
+	// QR, RD из запроса, RA; RCODE 3 - NXDOMAIN
+	flags := []byte{0x80 | query[2]&0x01, 0x80}
+	if !found {
+		flags[1] |= 3
+	}
+	var answer []byte
+	if found && qtype == 12 {
+		rdata := encodeName(hostname)
+		answer = append(answer,
+			0xc0, headerLen, // ссылка на имя в вопросе
+			0, 12, 0, 1, // PTR, IN
+			0, 0, 0, 60, // TTL
+			byte(len(rdata)>>8), byte(len(rdata)))
+		answer = append(answer, rdata...)
+	}
+	ancount := 0
+	if answer != nil {
+		ancount = 1
+	}
+
+	reply := []byte{query[0], query[1], flags[0], flags[1], 0, 1, 0, byte(ancount), 0, 0, 0, 0}
+	reply = append(reply, question...)
+	return append(reply, answer...)
+}
+
+func (s *stubDNS) queried(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, q := range s.queries {
+		if q == name {
+			return true
+		}
+	}
+	return false
+}
+
+// readName читает имя без сжатия, как оно приходит в вопросе запроса
+func readName(msg []byte, off int) (string, int, bool) {
+	var labels []string
+	for off < len(msg) {
+		n := int(msg[off])
+		off++
+		if n == 0 {
+			return strings.Join(labels, ".") + ".", off, true
+		}
+		if n > 63 || off+n > len(msg) {
+			return "", 0, false
+		}
+		labels = append(labels, strings.ToLower(string(msg[off:off+n])))
+		off += n
+	}
+	return "", 0, false
+}
+
+func encodeName(name string) []byte {
+	var b []byte
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	return append(b, 0)
+}
+
+func TestLookupServer(t *testing.T) {
+	stub := newStubDNS(t, map[string]string{
+		"1.2.0.192.in-addr.arpa.": "stub.example.com.",
+	})
+
+	cfg := testConfig()
+	cfg.Server = stub.addr()
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	r := New(cfg)
+	defer r.Close()
+
+	if got := waitLookup(t, r, "192.0.2.1"); got != "stub.example.com" {
+		t.Errorf("Lookup(192.0.2.1) = %q, want stub.example.com", got)
+	}
+	if got := waitLookup(t, r, "192.0.2.2"); got != "" {
+		t.Errorf("Lookup(192.0.2.2) = %q, want no hostname", got)
+	}
+	// Оба ответа получены от заданного сервера, а не от системного резолвера
+	for _, name := range []string{"1.2.0.192.in-addr.arpa.", "2.2.0.192.in-addr.arpa."} {
+		if !stub.queried(name) {
+			t.Errorf("server was not queried for %s", name)
+		}
+	}
+}
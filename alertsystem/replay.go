@@ -0,0 +1,142 @@
+package main
+
+import (
+	"alertsystem/aggregator"
+	"alertsystem/clickhouse"
+	"alertsystem/parser"
+	"bufio"
+	"chconfig"
+	"compress/gzip"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+// runReplay прогоняет архивный лог через парсер и правила.
+// Часами правил служит время записей, поэтому результат не зависит
+// от того, когда запущен повторный анализ.
+func runReplay(args []string) {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	configPath := flags.String("config", "config.yaml", "path to rules configuration file (YAML or JSON)")
+	source := flags.String("source", parser.SourceNginx, "parser to use: "+strings.Join(parser.Parsers(), ", "))
+	from := flags.String("from", "", "log file to replay, .gz files are decompressed")
+	output := flags.String("output", "clickhouse", "where to write alerts: clickhouse or stdout")
+	events := flags.Bool("events", false, "also write login events to clickhouse (only for logs that were never ingested)")
+	flags.Parse(args)
+
+	if *from == "" {
+		log.Fatal("replay: --from is required")
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	proxies, err := parser.NewTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		log.Fatalf("Failed to load trusted proxies: %v", err)
+	}
+	parse, ok := parser.Lookup(*source, proxies)
+	if !ok {
+		log.Fatalf("replay: unknown source %q, available: %s", *source, strings.Join(parser.Parsers(), ", "))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var sink aggregator.Sink
+	switch *output {
+	case "clickhouse":
+		chConfig, err := chconfig.FromEnv()
+		if err != nil {
+			log.Fatalf("Failed to load ClickHouse config: %v", err)
+		}
+		chClient, err := clickhouse.New(ctx, chConfig)
+		if err != nil {
+			log.Fatalf("Failed to create ClickHouse client: %v", err)
+		}
+		// spool принадлежит работающему сервису, повторный анализ его не использует
+		sink = clickhouse.NewWriter(chClient, cfg.Writer, nil)
+		if !*events {
+			sink = alertsOnlySink{sink}
+		}
+	case "stdout":
+		sink = aggregator.NewJSONSink(os.Stdout)
+	default:
+		log.Fatalf("replay: unknown output %q", *output)
+	}
+
+	agg, err := aggregator.New(ctx, sink, cfg, aggregator.Options{Replay: true})
+	if err != nil {
+		log.Fatalf("Failed to create aggregator: %v", err)
+	}
+
+	lines, err := replayFile(*from, func(line string) {
+		entry, err := parse(line)
+		if err != nil {
+			log.Printf("Failed to parse %s log: %v", *source, err)
+			return
+		}
+		agg.ProcessLog(entry)
+	})
+	// Close выдает события, ожидающие пару, поэтому вызывается до итогового сообщения
+	agg.Close()
+	if err != nil {
+		log.Fatalf("Replay failed after %d lines: %v", lines, err)
+	}
+	log.Printf("Replayed %d lines from %s", lines, *from)
+}
+
+// alertsOnlySink пропускает события входа: при повторном анализе уже
+// обработанных логов они есть в login_events, и новые ID продублировали бы их.
+// Ссылки алертов на события убираются - таких ID в login_events нет.
+type alertsOnlySink struct {
+	aggregator.Sink
+}
+
+func (s alertsOnlySink) InsertAlert(ctx context.Context, alert clickhouse.Alert) error {
+	alert.EventIDs = nil
+	return s.Sink.InsertAlert(ctx, alert)
+}
+
+func (s alertsOnlySink) InsertLoginEvent(ctx context.Context, event clickhouse.LoginEvent) error {
+	return nil
+}
+
+func replayFile(path string, onLine func(string)) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return 0, fmt.Errorf("failed to open gzip: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	lines := 0
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if line = strings.TrimRight(line, "\r\n"); line != "" {
+			onLine(line)
+			lines++
+		}
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil {
+			return lines, err
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package rules
+
+import (
+	"alertsystem/parser"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Сколько последних неудачных попыток хранить на пользователя: при переборе
+// их могут быть тысячи за окно, а для алерта достаточно последних
+const accountTakeoverMaxFailures = 1000
+
+var accountTakeoverDefaults = Config{
+	Threshold: 1, // неудачных попыток с других адресов перед успешным входом
+	Window:    1 * time.Hour,
+	Cooldown:  10 * time.Minute,
+	Severity:  SeverityCritical,
+	Sources:   []string{parser.SourceLogin},
+}
+
+type loginFailure struct {
+	remoteAddr string
+	time       time.Time
+	eventID    string
+}
+
+// accountAttack - последний алерт перебора или распыления по учетной записи
+type accountAttack struct {
+	alertType string
+	time      time.Time
+}
+
+// AccountTakeoverRule обнаруживает успешный вход в учетную запись, которую
+// перед этим атаковали: были неудачные попытки с других адресов или
+// сработало правило перебора либо распыления паролей.
+type AccountTakeoverRule struct {
+	cfg         Config
+	failures    map[string][]loginFailure // по имени пользователя
+	attacks     map[string]accountAttack
+	alerts      map[string]time.Time
+	lastCleanup time.Time
+}
+
+func NewAccountTakeoverRule(cfg Config) *AccountTakeoverRule {
+	return &AccountTakeoverRule{
+		cfg:      cfg,
+		failures: make(map[string][]loginFailure),
+		attacks:  make(map[string]accountAttack),
+		alerts:   make(map[string]time.Time),
+	}
+}
+
+func init() {
+	Register("account_takeover", accountTakeoverDefaults, func(cfg Config) Rule { return NewAccountTakeoverRule(cfg) })
+}
+
+func (r *AccountTakeoverRule) Name() string {
+	return "account_takeover"
+}
+
+func (r *AccountTakeoverRule) Sources() []string {
+	return r.cfg.Sources
+}
+
+func (r *AccountTakeoverRule) Reconfigure(cfg Config) {
+	r.cfg = cfg
+}
+
+// ObserveAlert запоминает атакованные учетные записи
+func (r *AccountTakeoverRule) ObserveAlert(alert parser.Alert) {
+	switch alert.Type {
+	case "bruteforce":
+		r.attacks[alert.Username] = accountAttack{alertType: alert.Type, time: alert.Date}
+	case "password_spraying":
+		// Затронутые пользователи перечислены в Samples
+		for _, username := range alert.Samples {
+			r.attacks[username] = accountAttack{alertType: alert.Type, time: alert.Date}
+		}
+	}
+}
+
+func (r *AccountTakeoverRule) Check(entry parser.LogEntry, now time.Time) []parser.Alert {
+	username := entry.GetUsername()
+	if !entry.IsLogin() || username == "" {
+		return nil
+	}
+
+	r.cleanup(now)
+
+	remoteAddr := entry.GetRemoteAddr()
+	switch entry.GetAuthStatus() {
+	case parser.AuthFailure:
+		failures := append(r.failures[username], loginFailure{
+			remoteAddr: remoteAddr,
+			time:       now,
+			eventID:    entry.GetEventID(),
+		})
+		if len(failures) > accountTakeoverMaxFailures {
+			failures = failures[len(failures)-accountTakeoverMaxFailures:]
+		}
+		r.failures[username] = failures
+		return nil
+	case parser.AuthSuccess:
+	default:
+		return nil
+	}
+
+	// Неудачные попытки с других адресов в пределах окна
+	otherAddrs := make(map[string]bool)
+	var count int
+	var eventIDs []string
+	for _, failure := range r.failures[username] {
+		if now.Sub(failure.time) > r.cfg.Window || failure.remoteAddr == remoteAddr {
+			continue
+		}
+		otherAddrs[failure.remoteAddr] = true
+		count++
+		eventIDs = appendEventID(eventIDs, failure.eventID)
+	}
+	attack, attacked := r.attacks[username]
+	attacked = attacked && now.Sub(attack.time) <= r.cfg.Window
+
+	// Успешный вход завершает эпизод: последующие входы оцениваются заново
+	delete(r.failures, username)
+	delete(r.attacks, username)
+
+	var reasons []string
+	if count > 0 && count >= r.cfg.Threshold {
+		reasons = append(reasons, fmt.Sprintf("%d failed attempts from %d other addresses", count, len(otherAddrs)))
+	}
+	if attacked {
+		reasons = append(reasons, "after "+attack.alertType+" alert")
+	}
+	if len(reasons) == 0 {
+		return nil
+	}
+
+	if lastAlert, exists := r.alerts[username]; exists && now.Sub(lastAlert) <= r.cfg.Cooldown {
+		return nil
+	}
+	r.alerts[username] = now
+
+	return []parser.Alert{{
+		Type:       "account_takeover",
+		Severity:   r.cfg.Severity,
+		Date:       now,
+		RemoteAddr: remoteAddr,
+		Action:     "login",
+		Username:   username,
+		Password:   entry.GetPassword(),
+		AuthStatus: parser.AuthSuccess,
+		Count:      count,
+		Details:    strings.Join(reasons, "; "),
+		EventIDs:   appendEventID(eventIDs, entry.GetEventID()),
+	}}
+}
+
+// cleanup удаляет устаревшие попытки и отметки об атаках; не чаще раза за окно
+func (r *AccountTakeoverRule) cleanup(now time.Time) {
+	if now.Sub(r.lastCleanup) <= r.cfg.Window {
+		return
+	}
+	r.lastCleanup = now
+
+	for username, failures := range r.failures {
+		if len(failures) == 0 || now.Sub(failures[len(failures)-1].time) > r.cfg.Window {
+			delete(r.failures, username)
+		}
+	}
+	for username, attack := range r.attacks {
+		if now.Sub(attack.time) > r.cfg.Window {
+			delete(r.attacks, username)
+		}
+	}
+	CleanupOldAlerts(r.alerts, now, r.cfg.Cooldown)
+}
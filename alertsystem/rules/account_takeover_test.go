@@ -0,0 +1,207 @@
+package rules
+
+import (
+	"alertsystem/parser"
+	"slices"
+	"testing"
+	"time"
+)
+
+func loginAt(status, remoteAddr, eventID string) parser.LoginEvent {
+	return parser.LoginEvent{
+		ID:         eventID,
+		RemoteAddr: remoteAddr,
+		Username:   "admin",
+		Password:   "secret",
+		AuthStatus: status,
+	}
+}
+
+func TestAccountTakeover(t *testing.T) {
+	failure := func(remoteAddr, eventID string) parser.LoginEvent {
+		return loginAt(parser.AuthFailure, remoteAddr, eventID)
+	}
+	success := func(remoteAddr, eventID string) parser.LoginEvent {
+		return loginAt(parser.AuthSuccess, remoteAddr, eventID)
+	}
+
+	// step - событие входа или алерт другого правила в момент at.
+	// wantDetails - ожидаемое описание алерта, пустое - алерта нет.
+	type step struct {
+		at          time.Duration
+		event       parser.LoginEvent
+		observe     *parser.Alert
+		wantDetails string
+		wantIDs     []string
+	}
+	tests := []struct {
+		name  string
+		cfg   Config
+		steps []step
+	}{
+		{
+			name: "success after failures from other addresses",
+			steps: []step{
+				{at: 0, event: failure("198.51.100.1", "f1")},
+				{at: time.Minute, event: failure("198.51.100.2", "f2")},
+				{at: 2 * time.Minute, event: failure("198.51.100.2", "f3")},
+				{
+					at: 3 * time.Minute, event: success("203.0.113.10", "s1"),
+					wantDetails: "3 failed attempts from 2 other addresses",
+					wantIDs:     []string{"f1", "f2", "f3", "s1"},
+				},
+			},
+		},
+		{
+			name: "failures from the same address",
+			steps: []step{
+				{at: 0, event: failure("203.0.113.10", "f1")},
+				{at: time.Minute, event: success("203.0.113.10", "s1")},
+			},
+		},
+		{
+			name: "failures outside window",
+			steps: []step{
+				{at: 0, event: failure("198.51.100.1", "f1")},
+				{at: time.Hour + time.Minute, event: success("203.0.113.10", "s1")},
+			},
+		},
+		{
+			name: "fewer failures than threshold",
+			cfg:  Config{Threshold: 3},
+			steps: []step{
+				{at: 0, event: failure("198.51.100.1", "f1")},
+				{at: time.Minute, event: failure("198.51.100.2", "f2")},
+				{at: 2 * time.Minute, event: success("203.0.113.10", "s1")},
+			},
+		},
+		{
+			name: "errors are not failures",
+			steps: []step{
+				{at: 0, event: loginAt(parser.AuthError, "198.51.100.1", "e1")},
+				{at: time.Minute, event: success("203.0.113.10", "s1")},
+			},
+		},
+		{
+			name: "success after bruteforce alert",
+			steps: []step{
+				{at: 0, observe: &parser.Alert{Type: "bruteforce", Username: "admin", Date: testNow}},
+				{
+					at: 10 * time.Minute, event: success("203.0.113.10", "s1"),
+					wantDetails: "after bruteforce alert",
+					wantIDs:     []string{"s1"},
+				},
+			},
+		},
+		{
+			name: "success after password spraying alert",
+			steps: []step{
+				{at: 0, observe: &parser.Alert{Type: "password_spraying", Samples: []string{"alice", "admin"}, Date: testNow}},
+				{
+					at: 10 * time.Minute, event: success("203.0.113.10", "s1"),
+					wantDetails: "after password_spraying alert",
+					wantIDs:     []string{"s1"},
+				},
+			},
+		},
+		{
+			name: "failures and attack alert together",
+			steps: []step{
+				{at: 0, event: failure("198.51.100.1", "f1")},
+				{at: 0, observe: &parser.Alert{Type: "bruteforce", Username: "admin", Date: testNow}},
+				{
+					at: time.Minute, event: success("203.0.113.10", "s1"),
+					wantDetails: "1 failed attempts from 1 other addresses; after bruteforce alert",
+					wantIDs:     []string{"f1", "s1"},
+				},
+			},
+		},
+		{
+			name: "alerts of other rules are ignored",
+			steps: []step{
+				{at: 0, observe: &parser.Alert{Type: "path_scan", Username: "admin", Date: testNow}},
+				{at: time.Minute, event: success("203.0.113.10", "s1")},
+			},
+		},
+		{
+			name: "attack alert outside window",
+			steps: []step{
+				{at: 0, observe: &parser.Alert{Type: "bruteforce", Username: "admin", Date: testNow}},
+				{at: 2 * time.Hour, event: success("203.0.113.10", "s1")},
+			},
+		},
+		{
+			name: "success ends episode",
+			steps: []step{
+				{at: 0, event: failure("198.51.100.1", "f1")},
+				{
+					at: time.Minute, event: success("203.0.113.10", "s1"),
+					wantDetails: "1 failed attempts from 1 other addresses",
+					wantIDs:     []string{"f1", "s1"},
+				},
+				{at: 30 * time.Minute, event: success("192.0.2.1", "s2")},
+			},
+		},
+		{
+			name: "cooldown",
+			steps: []step{
+				{at: 0, event: failure("198.51.100.1", "f1")},
+				{
+					at: time.Minute, event: success("203.0.113.10", "s1"),
+					wantDetails: "1 failed attempts from 1 other addresses",
+					wantIDs:     []string{"f1", "s1"},
+				},
+				{at: 2 * time.Minute, event: failure("198.51.100.2", "f2")},
+				{at: 3 * time.Minute, event: success("203.0.113.10", "s2")},
+				{at: 12 * time.Minute, event: failure("198.51.100.3", "f3")},
+				{
+					at: 13 * time.Minute, event: success("203.0.113.10", "s3"),
+					wantDetails: "1 failed attempts from 1 other addresses",
+					wantIDs:     []string{"f3", "s3"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := NewAccountTakeoverRule(tt.cfg.withDefaults(accountTakeoverDefaults))
+			for i, s := range tt.steps {
+				if s.observe != nil {
+					rule.ObserveAlert(*s.observe)
+					continue
+				}
+				alerts := rule.Check(s.event, testNow.Add(s.at))
+				if s.wantDetails == "" {
+					if len(alerts) != 0 {
+						t.Errorf("step %d: unexpected alert %+v", i, alerts[0])
+					}
+					continue
+				}
+				if len(alerts) != 1 {
+					t.Fatalf("step %d: got %d alerts, want 1", i, len(alerts))
+				}
+				alert := alerts[0]
+				if alert.Details != s.wantDetails {
+					t.Errorf("step %d: details = %q, want %q", i, alert.Details, s.wantDetails)
+				}
+				if !slices.Equal(alert.EventIDs, s.wantIDs) {
+					t.Errorf("step %d: event IDs = %v, want %v", i, alert.EventIDs, s.wantIDs)
+				}
+				if alert.RemoteAddr != s.event.RemoteAddr || alert.Username != "admin" {
+					t.Errorf("step %d: alert for %s/%s, want %s/admin", i, alert.RemoteAddr, alert.Username, s.event.RemoteAddr)
+				}
+			}
+		})
+	}
+}
+
+func TestAccountTakeoverFailuresCap(t *testing.T) {
+	rule := NewAccountTakeoverRule(accountTakeoverDefaults)
+	for i := 0; i < accountTakeoverMaxFailures+500; i++ {
+		rule.Check(loginAt(parser.AuthFailure, "198.51.100.1", ""), testNow.Add(time.Duration(i)*time.Millisecond))
+	}
+	if n := len(rule.failures["admin"]); n != accountTakeoverMaxFailures {
+		t.Errorf("stored failures = %d, want %d", n, accountTakeoverMaxFailures)
+	}
+}
@@ -5,56 +5,90 @@ import (
 	"time"
 )
 
-const (
-	bruteForceAttemptsThreshold = 5
-	bruteForceWindow            = 1 * time.Minute
-	bruteForceAlertCooldown     = 1 * time.Minute
-)
+var bruteForceDefaults = Config{
+	Threshold: 5,
+	Window:    1 * time.Minute,
+	Cooldown:  1 * time.Minute,
+	Severity:  SeverityHigh,
+	Sources:   []string{parser.SourceLogin},
+}
+
+type failedLogin struct {
+	time    time.Time
+	eventID string
+}
 
 type BruteforceRule struct {
-	failedLogins map[string][]time.Time
+	cfg          Config
+	failedLogins map[string][]failedLogin
 	alerts       map[string]time.Time
 }
 
-func NewBruteforceRule() *BruteforceRule {
+func NewBruteforceRule(cfg Config) *BruteforceRule {
 	return &BruteforceRule{
-		failedLogins: make(map[string][]time.Time),
+		cfg:          cfg,
+		failedLogins: make(map[string][]failedLogin),
 		alerts:       make(map[string]time.Time),
 	}
 }
 
-func (r *BruteforceRule) Check(log parser.NginxLog, now time.Time) *parser.Alert {
-	// Проверяем только логины с неудачным статусом (200)
-	if !log.IsLogin() || log.Status != "200" {
+func init() {
+	Register("bruteforce", bruteForceDefaults, func(cfg Config) Rule { return NewBruteforceRule(cfg) })
+}
+
+func (r *BruteforceRule) Name() string {
+	return "bruteforce"
+}
+
+func (r *BruteforceRule) Sources() []string {
+	return r.cfg.Sources
+}
+
+func (r *BruteforceRule) Reconfigure(cfg Config) {
+	r.cfg = cfg
+}
+
+func (r *BruteforceRule) Check(entry parser.LogEntry, now time.Time) []parser.Alert {
+	// Проверяем только неудачные попытки входа
+	if !entry.IsLogin() || entry.GetAuthStatus() != parser.AuthFailure {
 		return nil
 	}
 
-	username := log.Username
-	r.failedLogins[username] = append(r.failedLogins[username], now)
+	username := entry.GetUsername()
+	r.failedLogins[username] = append(r.failedLogins[username], failedLogin{
+		time:    now,
+		eventID: entry.GetEventID(),
+	})
 
 	// Очистка старых попыток
-	var recentAttempts []time.Time
-	for _, t := range r.failedLogins[username] {
-		if now.Sub(t) <= bruteForceWindow {
-			recentAttempts = append(recentAttempts, t)
+	var recentAttempts []failedLogin
+	for _, attempt := range r.failedLogins[username] {
+		if now.Sub(attempt.time) <= r.cfg.Window {
+			recentAttempts = append(recentAttempts, attempt)
 		}
 	}
 	r.failedLogins[username] = recentAttempts
 
 	// Проверка условий для алерта
-	if len(r.failedLogins[username]) >= bruteForceAttemptsThreshold {
-		if lastAlert, exists := r.alerts[username]; !exists || now.Sub(lastAlert) > bruteForceAlertCooldown {
+	if count := len(r.failedLogins[username]); count >= r.cfg.Threshold {
+		if lastAlert, exists := r.alerts[username]; !exists || now.Sub(lastAlert) > r.cfg.Cooldown {
 			r.alerts[username] = now
+			var eventIDs []string
+			for _, attempt := range r.failedLogins[username] {
+				eventIDs = appendEventID(eventIDs, attempt.eventID)
+			}
 			delete(r.failedLogins, username)
-			
-			return &parser.Alert{
+
+			return []parser.Alert{{
 				Type:       "bruteforce",
-				Date:       log.TimeLocal,
-				RemoteAddr: log.RemoteAddr,
+				Severity:   r.cfg.Severity,
+				Date:       now,
+				RemoteAddr: entry.GetRemoteAddr(),
 				Action:     "login",
 				Username:   username,
-				Count:      len(r.failedLogins[username]),
-			}
+				Count:      count,
+				EventIDs:   eventIDs,
+			}}
 		}
 	}
 	return nil
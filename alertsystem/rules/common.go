@@ -8,4 +8,11 @@ func CleanupOldAlerts(alerts map[string]time.Time, now time.Time, cooldown time.
 			delete(alerts, key)
 		}
 	}
-}
\ No newline at end of file
+}
+// appendEventID добавляет идентификатор события, пропуская записи без него
+func appendEventID(ids []string, id string) []string {
+	if id == "" {
+		return ids
+	}
+	return append(ids, id)
+}
@@ -0,0 +1,111 @@
+package rules
+
+import (
+	"alertsystem/parser"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Уровни критичности алертов
+const (
+	SeverityInfo     = "info"
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+// Config - параметры правила, задаваемые в конфигурационном файле.
+// Нулевые значения означают "использовать значение по умолчанию".
+type Config struct {
+	Enabled   *bool         `yaml:"enabled"`
+	Threshold int           `yaml:"threshold"`
+	Window    time.Duration `yaml:"window"`
+	Cooldown  time.Duration `yaml:"cooldown"`
+	Severity  string        `yaml:"severity"`
+	Sources   []string      `yaml:"sources"`
+}
+
+func (c Config) IsEnabled() bool {
+	return c.Enabled == nil || *c.Enabled
+}
+
+// withDefaults дополняет незаданные поля значениями по умолчанию
+func (c Config) withDefaults(def Config) Config {
+	if c.Enabled == nil {
+		c.Enabled = def.Enabled
+	}
+	if c.Threshold == 0 {
+		c.Threshold = def.Threshold
+	}
+	if c.Window == 0 {
+		c.Window = def.Window
+	}
+	if c.Cooldown == 0 {
+		c.Cooldown = def.Cooldown
+	}
+	if c.Severity == "" {
+		c.Severity = def.Severity
+	}
+	if len(c.Sources) == 0 {
+		c.Sources = def.Sources
+	}
+	return c
+}
+
+func (c Config) validate() error {
+	var errs []error
+	if c.Threshold < 0 {
+		errs = append(errs, fmt.Errorf("threshold must not be negative, got %d", c.Threshold))
+	}
+	if c.Window < 0 {
+		errs = append(errs, fmt.Errorf("window must not be negative, got %s", c.Window))
+	}
+	if c.Cooldown < 0 {
+		errs = append(errs, fmt.Errorf("cooldown must not be negative, got %s", c.Cooldown))
+	}
+	switch c.Severity {
+	case "", SeverityInfo, SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical:
+	default:
+		errs = append(errs, fmt.Errorf("unknown severity %q", c.Severity))
+	}
+	for _, source := range c.Sources {
+		if !isKnownSource(source) {
+			errs = append(errs, fmt.Errorf("unknown source %q", source))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ValidateConfigs проверяет настройки правил, включая имена правил
+func ValidateConfigs(configs map[string]Config) error {
+	names := make([]string, 0, len(configs))
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		cfg := configs[name]
+		if _, ok := factories[name]; !ok {
+			errs = append(errs, fmt.Errorf("rule %q: unknown rule", name))
+			continue
+		}
+		if err := cfg.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("rule %q: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func isKnownSource(source string) bool {
+	for _, s := range parser.Sources {
+		if s == source {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,191 @@
+package rules
+
+import (
+	"alertsystem/parser"
+	"fmt"
+	"net/netip"
+	"time"
+)
+
+// Сколько пар логин/пароль из окна перечислять в алерте
+const credentialStuffingSamples = 10
+
+var credentialStuffingDefaults = Config{
+	Threshold: 10,
+	Window:    5 * time.Minute,
+	Cooldown:  5 * time.Minute,
+	Severity:  SeverityHigh,
+	Sources:   []string{parser.SourceLogin},
+}
+
+type credentialAttempt struct {
+	username   string
+	password   string
+	remoteAddr string
+	time       time.Time
+	eventID    string
+}
+
+// CredentialStuffingRule обнаруживает проверку утекших баз: много разных пар
+// логин/пароль с одного IP или из одной подсети /24 (/64 для IPv6) за окно.
+// В отличие от перебора (один пользователь) и распыления (один пароль)
+// здесь различаются и логины, и пароли: правило срабатывает, только если
+// и тех и других не меньше половины от числа пар.
+type CredentialStuffingRule struct {
+	cfg         Config
+	attempts    map[string][]credentialAttempt // по IP и по подсети
+	alerts      map[string]time.Time
+	lastCleanup time.Time
+}
+
+func NewCredentialStuffingRule(cfg Config) *CredentialStuffingRule {
+	return &CredentialStuffingRule{
+		cfg:      cfg,
+		attempts: make(map[string][]credentialAttempt),
+		alerts:   make(map[string]time.Time),
+	}
+}
+
+func init() {
+	Register("credential_stuffing", credentialStuffingDefaults, func(cfg Config) Rule { return NewCredentialStuffingRule(cfg) })
+}
+
+func (r *CredentialStuffingRule) Name() string {
+	return "credential_stuffing"
+}
+
+func (r *CredentialStuffingRule) Sources() []string {
+	return r.cfg.Sources
+}
+
+func (r *CredentialStuffingRule) Reconfigure(cfg Config) {
+	r.cfg = cfg
+}
+
+func (r *CredentialStuffingRule) Check(entry parser.LogEntry, now time.Time) []parser.Alert {
+	if !entry.IsLogin() || entry.GetUsername() == "" {
+		return nil
+	}
+
+	r.cleanup(now)
+
+	attempt := credentialAttempt{
+		username:   entry.GetUsername(),
+		password:   entry.GetPassword(),
+		remoteAddr: entry.GetRemoteAddr(),
+		time:       now,
+		eventID:    entry.GetEventID(),
+	}
+
+	var alerts []parser.Alert
+	if alert, ok := r.track(attempt.remoteAddr, attempt, false); ok {
+		alerts = append(alerts, alert)
+	}
+	if subnet, ok := subnetOf(attempt.remoteAddr); ok {
+		if alert, ok := r.track(subnet, attempt, true); ok {
+			alerts = append(alerts, alert)
+		}
+	}
+	return alerts
+}
+
+// track добавляет попытку к окну ключа (IP или подсети) и проверяет условие
+func (r *CredentialStuffingRule) track(key string, attempt credentialAttempt, subnet bool) (parser.Alert, bool) {
+	r.attempts[key] = append(r.attempts[key], attempt)
+
+	// Очистка старых попыток
+	var recentAttempts []credentialAttempt
+	for _, a := range r.attempts[key] {
+		if attempt.time.Sub(a.time) <= r.cfg.Window {
+			recentAttempts = append(recentAttempts, a)
+		}
+	}
+	r.attempts[key] = recentAttempts
+
+	// Уникальные пары, логины, пароли и адреса
+	type pair struct{ username, password string }
+	pairs := make(map[pair]bool)
+	usernames := make(map[string]bool)
+	passwords := make(map[string]bool)
+	addrs := make(map[string]bool)
+	var samples, eventIDs []string
+	for _, a := range recentAttempts {
+		usernames[a.username] = true
+		passwords[a.password] = true
+		addrs[a.remoteAddr] = true
+		eventIDs = appendEventID(eventIDs, a.eventID)
+
+		p := pair{a.username, a.password}
+		if pairs[p] {
+			continue
+		}
+		pairs[p] = true
+		if len(samples) < credentialStuffingSamples {
+			samples = append(samples, a.username+":"+a.password)
+		}
+	}
+
+	// Атака с одного адреса уже обнаруживается по ключу IP
+	if subnet && len(addrs) < 2 {
+		return parser.Alert{}, false
+	}
+	if len(pairs) < r.cfg.Threshold || 2*len(usernames) < len(pairs) || 2*len(passwords) < len(pairs) {
+		return parser.Alert{}, false
+	}
+
+	if lastAlert, exists := r.alerts[key]; exists && attempt.time.Sub(lastAlert) <= r.cfg.Cooldown {
+		return parser.Alert{}, false
+	}
+	r.alerts[key] = attempt.time
+	delete(r.attempts, key)
+
+	// Для подсети в алерт попадает адрес последней попытки, чтобы работали
+	// обогащение и поиск по remote_addr, а сама подсеть - в Details
+	details := "single address"
+	if subnet {
+		details = fmt.Sprintf("subnet %s, %d addresses", key, len(addrs))
+	}
+	return parser.Alert{
+		Type:       "credential_stuffing",
+		Severity:   r.cfg.Severity,
+		Date:       attempt.time,
+		RemoteAddr: attempt.remoteAddr,
+		Action:     "login",
+		Count:      len(pairs),
+		Details:    details,
+		Samples:    samples,
+		EventIDs:   eventIDs,
+	}, true
+}
+
+// cleanup удаляет ключи без попыток в пределах окна; не чаще раза за окно
+func (r *CredentialStuffingRule) cleanup(now time.Time) {
+	if now.Sub(r.lastCleanup) <= r.cfg.Window {
+		return
+	}
+	r.lastCleanup = now
+
+	for key, attempts := range r.attempts {
+		if len(attempts) == 0 || now.Sub(attempts[len(attempts)-1].time) > r.cfg.Window {
+			delete(r.attempts, key)
+		}
+	}
+	CleanupOldAlerts(r.alerts, now, r.cfg.Cooldown)
+}
+
+// subnetOf возвращает подсеть адреса: /24 для IPv4, /64 для IPv6
+func subnetOf(remoteAddr string) (string, bool) {
+	addr, err := netip.ParseAddr(remoteAddr)
+	if err != nil {
+		return "", false
+	}
+	bits := 64
+	if addr.Unmap().Is4() {
+		addr, bits = addr.Unmap(), 24
+	}
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return "", false
+	}
+	return prefix.String(), true
+}
@@ -0,0 +1,190 @@
+package rules
+
+import (
+	"alertsystem/parser"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSubnetOf(t *testing.T) {
+	tests := []struct {
+		addr   string
+		subnet string
+		ok     bool
+	}{
+		{"203.0.113.77", "203.0.113.0/24", true},
+		{"::ffff:203.0.113.77", "203.0.113.0/24", true},
+		{"2001:db8:1:2:3:4:5:6", "2001:db8:1:2::/64", true},
+		{"localhost", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		subnet, ok := subnetOf(tt.addr)
+		if subnet != tt.subnet || ok != tt.ok {
+			t.Errorf("subnetOf(%q) = %q, %v, want %q, %v", tt.addr, subnet, ok, tt.subnet, tt.ok)
+		}
+	}
+}
+
+func TestCredentialStuffing(t *testing.T) {
+	// attempt - попытка входа "адрес логин:пароль"
+	type attempt struct {
+		addr, username, password string
+	}
+	// distinct - count попыток с разными логинами и паролями с адресов addrs по кругу
+	distinct := func(count int, addrs ...string) []attempt {
+		var attempts []attempt
+		for i := 0; i < count; i++ {
+			attempts = append(attempts, attempt{
+				addr:     addrs[i%len(addrs)],
+				username: fmt.Sprintf("user%d", i),
+				password: fmt.Sprintf("pass%d", i),
+			})
+		}
+		return attempts
+	}
+	type wantAlert struct {
+		remoteAddr string
+		details    string
+		count      int
+	}
+
+	tests := []struct {
+		name     string
+		interval time.Duration
+		attempts []attempt
+		want     []wantAlert
+	}{
+		{
+			name:     "single address",
+			attempts: distinct(4, "203.0.113.10"),
+			want:     []wantAlert{{"203.0.113.10", "single address", 4}},
+		},
+		{
+			name:     "spread over subnet",
+			attempts: distinct(4, "203.0.113.1", "203.0.113.2", "203.0.113.3", "203.0.113.4"),
+			// Адрес алерта - последний адрес, а не подсеть: по нему работают обогащение и поиск
+			want: []wantAlert{{"203.0.113.4", "subnet 203.0.113.0/24, 4 addresses", 4}},
+		},
+		{
+			name:     "two addresses in subnet",
+			attempts: distinct(4, "203.0.113.1", "203.0.113.1", "203.0.113.1", "203.0.113.2"),
+			want:     []wantAlert{{"203.0.113.2", "subnet 203.0.113.0/24, 2 addresses", 4}},
+		},
+		{
+			name:     "IPv6 subnet",
+			attempts: distinct(4, "2001:db8::1", "2001:db8::2"),
+			want:     []wantAlert{{"2001:db8::2", "subnet 2001:db8::/64, 2 addresses", 4}},
+		},
+		{
+			name:     "different subnets",
+			attempts: distinct(4, "203.0.113.1", "198.51.100.1", "192.0.2.1", "203.0.114.1"),
+		},
+		{
+			name: "one username is bruteforce",
+			attempts: []attempt{
+				{"203.0.113.10", "admin", "123456"},
+				{"203.0.113.10", "admin", "qwerty"},
+				{"203.0.113.10", "admin", "password"},
+				{"203.0.113.10", "admin", "letmein"},
+			},
+		},
+		{
+			name: "one password is spraying",
+			attempts: []attempt{
+				{"203.0.113.10", "alice", "Winter2026"},
+				{"203.0.113.10", "bob", "Winter2026"},
+				{"203.0.113.10", "carol", "Winter2026"},
+				{"203.0.113.10", "dave", "Winter2026"},
+			},
+		},
+		{
+			name: "half of pairs share usernames",
+			attempts: []attempt{
+				{"203.0.113.10", "alice", "pass1"},
+				{"203.0.113.10", "alice", "pass2"},
+				{"203.0.113.10", "bob", "pass3"},
+				{"203.0.113.10", "bob", "pass4"},
+			},
+			want: []wantAlert{{"203.0.113.10", "single address", 4}},
+		},
+		{
+			name: "repeated pair counts once",
+			attempts: []attempt{
+				{"203.0.113.10", "alice", "pass1"},
+				{"203.0.113.10", "alice", "pass1"},
+				{"203.0.113.10", "bob", "pass2"},
+				{"203.0.113.10", "carol", "pass3"},
+			},
+		},
+		{
+			name:     "attempts outside window",
+			interval: 2 * time.Minute,
+			attempts: distinct(4, "203.0.113.10"),
+		},
+		{
+			name:     "cooldown",
+			attempts: distinct(8, "203.0.113.10"),
+			want:     []wantAlert{{"203.0.113.10", "single address", 4}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := NewCredentialStuffingRule(Config{Threshold: 4}.withDefaults(credentialStuffingDefaults))
+			interval := tt.interval
+			if interval == 0 {
+				interval = time.Second
+			}
+
+			var got []wantAlert
+			for i, a := range tt.attempts {
+				event := parser.LoginEvent{
+					ID:         fmt.Sprintf("e%d", i),
+					RemoteAddr: a.addr,
+					Username:   a.username,
+					Password:   a.password,
+					AuthStatus: parser.AuthFailure,
+				}
+				for _, alert := range rule.Check(event, testNow.Add(time.Duration(i)*interval)) {
+					got = append(got, wantAlert{alert.RemoteAddr, alert.Details, alert.Count})
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("alerts = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCredentialStuffingAlert(t *testing.T) {
+	rule := NewCredentialStuffingRule(Config{Threshold: 3}.withDefaults(credentialStuffingDefaults))
+	var alerts []parser.Alert
+	for i, pair := range [][2]string{{"alice", "pass1"}, {"bob", "pass2"}, {"carol", "pass3"}} {
+		event := parser.LoginEvent{
+			ID:         fmt.Sprintf("e%d", i),
+			RemoteAddr: "203.0.113.10",
+			Username:   pair[0],
+			Password:   pair[1],
+			AuthStatus: parser.AuthSuccess,
+		}
+		alerts = rule.Check(event, testNow.Add(time.Duration(i)*time.Second))
+	}
+	if len(alerts) != 1 {
+		t.Fatalf("got %d alerts, want 1", len(alerts))
+	}
+	alert := alerts[0]
+	if want := []string{"alice:pass1", "bob:pass2", "carol:pass3"}; !slices.Equal(alert.Samples, want) {
+		t.Errorf("samples = %v, want %v", alert.Samples, want)
+	}
+	if want := []string{"e0", "e1", "e2"}; !slices.Equal(alert.EventIDs, want) {
+		t.Errorf("event IDs = %v, want %v", alert.EventIDs, want)
+	}
+
+	// Попытки без имени пользователя не учитываются
+	if alerts := rule.Check(parser.LoginEvent{RemoteAddr: "198.51.100.1", AuthStatus: parser.AuthFailure}, testNow); alerts != nil {
+		t.Errorf("attempt without username raised %+v", alerts)
+	}
+}
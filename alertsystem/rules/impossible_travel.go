@@ -0,0 +1,158 @@
+package rules
+
+import (
+	"alertsystem/parser"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Ближе этого расстояния входы не сравниваются: точность GeoIP для городов -
+// десятки и сотни километров
+const minTravelDistanceKm = 500
+
+const earthRadiusKm = 6371
+
+var impossibleTravelDefaults = Config{
+	Threshold: 1000, // максимальная правдоподобная скорость перемещения, км/ч
+	Window:    24 * time.Hour,
+	Cooldown:  1 * time.Hour,
+	Severity:  SeverityHigh,
+	Sources:   []string{parser.SourceLogin},
+}
+
+type successfulLogin struct {
+	remoteAddr string
+	time       time.Time
+	geo        parser.Geo
+	eventID    string
+}
+
+// ImpossibleTravelRule обнаруживает успешные входы одного пользователя
+// из удаленных друг от друга мест за время, за которое туда не добраться.
+// Местоположение берется из базы GeoIP; без нее правило не срабатывает.
+type ImpossibleTravelRule struct {
+	cfg         Config
+	lastLogins  map[string]successfulLogin // по имени пользователя
+	alerts      map[string]time.Time
+	lastCleanup time.Time
+}
+
+func NewImpossibleTravelRule(cfg Config) *ImpossibleTravelRule {
+	return &ImpossibleTravelRule{
+		cfg:        cfg,
+		lastLogins: make(map[string]successfulLogin),
+		alerts:     make(map[string]time.Time),
+	}
+}
+
+func init() {
+	Register("impossible_travel", impossibleTravelDefaults, func(cfg Config) Rule { return NewImpossibleTravelRule(cfg) })
+}
+
+func (r *ImpossibleTravelRule) Name() string {
+	return "impossible_travel"
+}
+
+func (r *ImpossibleTravelRule) Sources() []string {
+	return r.cfg.Sources
+}
+
+func (r *ImpossibleTravelRule) Reconfigure(cfg Config) {
+	r.cfg = cfg
+}
+
+func (r *ImpossibleTravelRule) Check(entry parser.LogEntry, now time.Time) []parser.Alert {
+	// Местоположение определяется только для объединенных событий входа
+	event, ok := entry.(parser.LoginEvent)
+	if !ok || event.AuthStatus != parser.AuthSuccess || event.Username == "" {
+		return nil
+	}
+	if event.Geo == nil || !event.Geo.HasCoordinates {
+		return nil
+	}
+
+	r.cleanup(now)
+
+	current := successfulLogin{
+		remoteAddr: event.RemoteAddr,
+		time:       now,
+		geo:        *event.Geo,
+		eventID:    event.ID,
+	}
+	previous, exists := r.lastLogins[event.Username]
+	r.lastLogins[event.Username] = current
+
+	if !exists || previous.remoteAddr == current.remoteAddr {
+		return nil
+	}
+	elapsed := now.Sub(previous.time)
+	if elapsed < 0 {
+		elapsed = -elapsed
+	}
+	if elapsed > r.cfg.Window {
+		return nil
+	}
+
+	distance := distanceKm(previous.geo, current.geo)
+	if distance < minTravelDistanceKm {
+		return nil
+	}
+	if hours := elapsed.Hours(); hours > 0 && distance/hours <= float64(r.cfg.Threshold) {
+		return nil
+	}
+
+	if lastAlert, exists := r.alerts[event.Username]; exists && now.Sub(lastAlert) <= r.cfg.Cooldown {
+		return nil
+	}
+	r.alerts[event.Username] = now
+
+	return []parser.Alert{{
+		Type:       "impossible_travel",
+		Severity:   r.cfg.Severity,
+		Date:       now,
+		RemoteAddr: event.RemoteAddr,
+		Action:     "login",
+		Username:   event.Username,
+		AuthStatus: parser.AuthSuccess,
+		Count:      int(math.Round(distance)),
+		Details: fmt.Sprintf("%s (%s) -> %s (%s): %.0f km in %s",
+			previous.remoteAddr, place(previous.geo), current.remoteAddr, place(current.geo),
+			distance, elapsed.Round(time.Second)),
+		EventIDs: appendEventID(appendEventID(nil, previous.eventID), current.eventID),
+	}}
+}
+
+// cleanup удаляет входы старше окна; не чаще раза за окно
+func (r *ImpossibleTravelRule) cleanup(now time.Time) {
+	if now.Sub(r.lastCleanup) <= r.cfg.Window {
+		return
+	}
+	r.lastCleanup = now
+
+	for username, login := range r.lastLogins {
+		if now.Sub(login.time) > r.cfg.Window {
+			delete(r.lastLogins, username)
+		}
+	}
+	CleanupOldAlerts(r.alerts, now, r.cfg.Cooldown)
+}
+
+// distanceKm - расстояние по поверхности Земли (формула гаверсинусов)
+func distanceKm(a, b parser.Geo) float64 {
+	lat1 := a.Latitude * math.Pi / 180
+	lat2 := b.Latitude * math.Pi / 180
+	dLat := lat2 - lat1
+	dLon := (b.Longitude - a.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
+
+// place описывает местоположение как "город, страна"
+func place(geo parser.Geo) string {
+	if geo.City == "" {
+		return geo.Country
+	}
+	return geo.City + ", " + geo.Country
+}
@@ -0,0 +1,185 @@
+package rules
+
+import (
+	"alertsystem/parser"
+	"math"
+	"testing"
+	"time"
+)
+
+var (
+	moscow = parser.Geo{Country: "RU", City: "Moscow", Latitude: 55.7558, Longitude: 37.6173, HasCoordinates: true}
+	tula   = parser.Geo{Country: "RU", City: "Tula", Latitude: 54.1931, Longitude: 37.6177, HasCoordinates: true}
+	berlin = parser.Geo{Country: "DE", City: "Berlin", Latitude: 52.5200, Longitude: 13.4050, HasCoordinates: true}
+	// Известна только страна: координаты центра страны не сравниваются
+	countryOnly = parser.Geo{Country: "DE"}
+)
+
+func TestDistanceKm(t *testing.T) {
+	if d := distanceKm(moscow, berlin); math.Abs(d-1609) > 5 {
+		t.Errorf("Moscow-Berlin = %.0f km, want about 1609", d)
+	}
+	if d := distanceKm(moscow, moscow); d != 0 {
+		t.Errorf("same place = %f km, want 0", d)
+	}
+}
+
+func TestImpossibleTravel(t *testing.T) {
+	login := func(remoteAddr string, geo *parser.Geo, eventID string) parser.LoginEvent {
+		event := loginAt(parser.AuthSuccess, remoteAddr, eventID)
+		event.Geo = geo
+		return event
+	}
+
+	type step struct {
+		at        time.Duration
+		event     parser.LoginEvent
+		wantAlert bool
+	}
+	tests := []struct {
+		name  string
+		cfg   Config
+		steps []step
+	}{
+		{
+			name: "too fast",
+			steps: []step{
+				{at: 0, event: login("203.0.113.10", &moscow, "e1")},
+				{at: time.Hour, event: login("198.51.100.1", &berlin, "e2"), wantAlert: true},
+			},
+		},
+		{
+			name: "simultaneous logins",
+			steps: []step{
+				{at: 0, event: login("203.0.113.10", &moscow, "e1")},
+				{at: 0, event: login("198.51.100.1", &berlin, "e2"), wantAlert: true},
+			},
+		},
+		{
+			name: "plausible speed",
+			steps: []step{
+				{at: 0, event: login("203.0.113.10", &moscow, "e1")},
+				{at: 2 * time.Hour, event: login("198.51.100.1", &berlin, "e2")},
+			},
+		},
+		{
+			name: "higher speed threshold",
+			cfg:  Config{Threshold: 2000},
+			steps: []step{
+				{at: 0, event: login("203.0.113.10", &moscow, "e1")},
+				{at: time.Hour, event: login("198.51.100.1", &berlin, "e2")},
+			},
+		},
+		{
+			name: "closer than minimum distance",
+			steps: []step{
+				{at: 0, event: login("203.0.113.10", &moscow, "e1")},
+				{at: time.Minute, event: login("198.51.100.1", &tula, "e2")},
+			},
+		},
+		{
+			name: "same address, location changed",
+			steps: []step{
+				{at: 0, event: login("203.0.113.10", &moscow, "e1")},
+				{at: time.Minute, event: login("203.0.113.10", &berlin, "e2")},
+			},
+		},
+		{
+			name: "previous login outside window",
+			steps: []step{
+				{at: 0, event: login("203.0.113.10", &moscow, "e1")},
+				{at: 25 * time.Hour, event: login("198.51.100.1", &berlin, "e2")},
+			},
+		},
+		{
+			name: "no location",
+			steps: []step{
+				{at: 0, event: login("203.0.113.10", &moscow, "e1")},
+				{at: time.Minute, event: login("198.51.100.1", nil, "e2")},
+				{at: 2 * time.Minute, event: login("198.51.100.2", &countryOnly, "e3")},
+			},
+		},
+		{
+			name: "logins without location are not remembered",
+			steps: []step{
+				{at: 0, event: login("203.0.113.10", &countryOnly, "e1")},
+				{at: time.Minute, event: login("198.51.100.1", &berlin, "e2")},
+			},
+		},
+		{
+			name: "failed login",
+			steps: []step{
+				{at: 0, event: login("203.0.113.10", &moscow, "e1")},
+				{at: time.Minute, event: parser.LoginEvent{
+					RemoteAddr: "198.51.100.1", Username: "admin", AuthStatus: parser.AuthFailure, Geo: &berlin,
+				}},
+			},
+		},
+		{
+			name: "compared with the latest login",
+			steps: []step{
+				{at: 0, event: login("203.0.113.10", &moscow, "e1")},
+				{at: 3 * time.Hour, event: login("198.51.100.1", &berlin, "e2")},
+				{at: 3*time.Hour + time.Minute, event: login("198.51.100.2", &berlin, "e3")},
+			},
+		},
+		{
+			name: "cooldown",
+			steps: []step{
+				{at: 0, event: login("203.0.113.10", &moscow, "e1")},
+				{at: time.Minute, event: login("198.51.100.1", &berlin, "e2"), wantAlert: true},
+				{at: 2 * time.Minute, event: login("203.0.113.10", &moscow, "e3")},
+				{at: 62 * time.Minute, event: login("198.51.100.1", &berlin, "e4"), wantAlert: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := NewImpossibleTravelRule(tt.cfg.withDefaults(impossibleTravelDefaults))
+			for i, s := range tt.steps {
+				alerts := rule.Check(s.event, testNow.Add(s.at))
+				if !s.wantAlert {
+					if len(alerts) != 0 {
+						t.Errorf("step %d: unexpected alert %+v", i, alerts[0])
+					}
+					continue
+				}
+				if len(alerts) != 1 {
+					t.Fatalf("step %d: got %d alerts, want 1", i, len(alerts))
+				}
+				alert := alerts[0]
+				if alert.RemoteAddr != s.event.RemoteAddr || alert.Username != "admin" {
+					t.Errorf("step %d: alert for %s/%s, want %s/admin", i, alert.RemoteAddr, alert.Username, s.event.RemoteAddr)
+				}
+				if alert.Count < minTravelDistanceKm {
+					t.Errorf("step %d: distance %d km is below minimum", i, alert.Count)
+				}
+				if len(alert.EventIDs) != 2 || alert.EventIDs[1] != s.event.ID {
+					t.Errorf("step %d: event IDs = %v, want previous and %s", i, alert.EventIDs, s.event.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestImpossibleTravelDetails(t *testing.T) {
+	rule := NewImpossibleTravelRule(impossibleTravelDefaults)
+	first := loginAt(parser.AuthSuccess, "203.0.113.10", "e1")
+	first.Geo = &moscow
+	second := loginAt(parser.AuthSuccess, "198.51.100.1", "e2")
+	second.Geo = &berlin
+
+	rule.Check(first, testNow)
+	alerts := rule.Check(second, testNow.Add(30*time.Minute))
+	if len(alerts) != 1 {
+		t.Fatalf("got %d alerts, want 1", len(alerts))
+	}
+	want := "203.0.113.10 (Moscow, RU) -> 198.51.100.1 (Berlin, DE): 1609 km in 30m0s"
+	if alerts[0].Details != want {
+		t.Errorf("details = %q, want %q", alerts[0].Details, want)
+	}
+	if alerts[0].Count != 1609 {
+		t.Errorf("count = %d, want 1609", alerts[0].Count)
+	}
+}
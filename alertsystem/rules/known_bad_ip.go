@@ -0,0 +1,77 @@
+package rules
+
+import (
+	"alertsystem/parser"
+	"strings"
+	"time"
+)
+
+var knownBadIPDefaults = Config{
+	Cooldown: 1 * time.Hour,
+	Severity: SeverityMedium,
+	Sources:  []string{parser.SourceNginx, parser.SourceWeb},
+}
+
+// KnownBadIPRule сообщает о запросах с адресов из списков репутации
+// (каталог threat_intel). Не больше одного алерта на IP за cooldown.
+type KnownBadIPRule struct {
+	cfg         Config
+	alerts      map[string]time.Time
+	lastCleanup time.Time
+}
+
+func NewKnownBadIPRule(cfg Config) *KnownBadIPRule {
+	return &KnownBadIPRule{
+		cfg:    cfg,
+		alerts: make(map[string]time.Time),
+	}
+}
+
+func init() {
+	Register("known_bad_ip", knownBadIPDefaults, func(cfg Config) Rule { return NewKnownBadIPRule(cfg) })
+}
+
+func (r *KnownBadIPRule) Name() string {
+	return "known_bad_ip"
+}
+
+func (r *KnownBadIPRule) Sources() []string {
+	return r.cfg.Sources
+}
+
+func (r *KnownBadIPRule) Reconfigure(cfg Config) {
+	r.cfg = cfg
+}
+
+func (r *KnownBadIPRule) Check(entry parser.LogEntry, now time.Time) []parser.Alert {
+	feeds := entry.GetThreatFeeds()
+	if len(feeds) == 0 {
+		return nil
+	}
+
+	if now.Sub(r.lastCleanup) > r.cfg.Cooldown {
+		CleanupOldAlerts(r.alerts, now, r.cfg.Cooldown)
+		r.lastCleanup = now
+	}
+
+	remoteAddr := entry.GetRemoteAddr()
+	if lastAlert, exists := r.alerts[remoteAddr]; exists && now.Sub(lastAlert) <= r.cfg.Cooldown {
+		return nil
+	}
+	r.alerts[remoteAddr] = now
+
+	alert := parser.Alert{
+		Type:       "known_bad_ip",
+		Severity:   r.cfg.Severity,
+		Date:       now,
+		RemoteAddr: remoteAddr,
+		Action:     "request",
+		Username:   entry.GetUsername(),
+		Details:    strings.Join(feeds, ", "),
+	}
+	if log, ok := entry.(parser.NginxLog); ok {
+		alert.Action = strings.ToLower(log.Method)
+		alert.RequestPath = log.Path
+	}
+	return []parser.Alert{alert}
+}
@@ -5,45 +5,67 @@ import (
 	"time"
 )
 
-const (
-	sprayAttemptsThreshold = 2
-	sprayWindow            = 1 * time.Minute
-	sprayAlertCooldown     = 1 * time.Minute
-)
+var sprayDefaults = Config{
+	Threshold: 2,
+	Window:    1 * time.Minute,
+	Cooldown:  1 * time.Minute,
+	Severity:  SeverityHigh,
+	Sources:   []string{parser.SourceLogin},
+}
 
 type passwordAttempt struct {
 	username string
 	time     time.Time
+	eventID  string
 }
 
 type PasswordSprayRule struct {
+	cfg      Config
 	attempts map[string][]passwordAttempt
 	alerts   map[string]time.Time
 }
 
-func NewPasswordSprayRule() *PasswordSprayRule {
+func NewPasswordSprayRule(cfg Config) *PasswordSprayRule {
 	return &PasswordSprayRule{
+		cfg:      cfg,
 		attempts: make(map[string][]passwordAttempt),
 		alerts:   make(map[string]time.Time),
 	}
 }
 
-func (r *PasswordSprayRule) Check(log parser.NginxLog, now time.Time) *parser.Alert {
-	// Проверяем только логины с неудачным статусом (200)
-	if !log.IsLogin() || log.Status != "200" {
+func init() {
+	Register("password_spraying", sprayDefaults, func(cfg Config) Rule { return NewPasswordSprayRule(cfg) })
+}
+
+func (r *PasswordSprayRule) Name() string {
+	return "password_spraying"
+}
+
+func (r *PasswordSprayRule) Sources() []string {
+	return r.cfg.Sources
+}
+
+func (r *PasswordSprayRule) Reconfigure(cfg Config) {
+	r.cfg = cfg
+}
+
+func (r *PasswordSprayRule) Check(entry parser.LogEntry, now time.Time) []parser.Alert {
+	// Проверяем только неудачные попытки входа
+	if !entry.IsLogin() || entry.GetAuthStatus() != parser.AuthFailure {
 		return nil
 	}
 
-	password := log.Password
+	password := entry.GetPassword()
 	r.attempts[password] = append(r.attempts[password], passwordAttempt{
-		username: log.Username,
+		username: entry.GetUsername(),
 		time:     now,
+		eventID:  entry.GetEventID(),
 	})
 
 	// Очистка старых попыток
 	var recentAttempts []passwordAttempt
 	for _, attempt := range r.attempts[password] {
-		if now.Sub(attempt.time) <= sprayWindow {
+		if now.Sub(attempt.time) <= r.cfg.Window {
 			recentAttempts = append(recentAttempts, attempt)
 		}
 	}
@@ -51,23 +73,31 @@ func (r *PasswordSprayRule) Check(log parser.NginxLog, now time.Time) *parser.Al
 
 	// Проверка уникальных пользователей
 	uniqueUsers := make(map[string]bool)
+	var usernames, eventIDs []string
 	for _, attempt := range r.attempts[password] {
-		uniqueUsers[attempt.username] = true
+		if !uniqueUsers[attempt.username] {
+			uniqueUsers[attempt.username] = true
+			usernames = append(usernames, attempt.username)
+		}
+		eventIDs = appendEventID(eventIDs, attempt.eventID)
 	}
 
-	if len(uniqueUsers) >= sprayAttemptsThreshold {
-		if lastAlert, exists := r.alerts[password]; !exists || now.Sub(lastAlert) > sprayAlertCooldown {
+	if len(uniqueUsers) >= r.cfg.Threshold {
+		if lastAlert, exists := r.alerts[password]; !exists || now.Sub(lastAlert) > r.cfg.Cooldown {
 			r.alerts[password] = now
 			delete(r.attempts, password)
-			
-			return &parser.Alert{
+
+			return []parser.Alert{{
 				Type:           "password_spraying",
-				Date:           log.TimeLocal,
-				RemoteAddr:     log.RemoteAddr,
+				Severity:       r.cfg.Severity,
+				Date:           now,
+				RemoteAddr:     entry.GetRemoteAddr(),
 				Action:         "login",
 				Count:          len(uniqueUsers),
 				CommonPassword: password,
-			}
+				Samples:        usernames,
+				EventIDs:       eventIDs,
+			}}
 		}
 	}
 	return nil
@@ -0,0 +1,124 @@
+package rules
+
+import (
+	"alertsystem/parser"
+	"strings"
+	"time"
+)
+
+// Сколько путей из окна перечислять в алерте
+const pathScanSamples = 10
+
+var pathScanDefaults = Config{
+	Threshold: 20,
+	Window:    1 * time.Minute,
+	Cooldown:  5 * time.Minute,
+	Severity:  SeverityMedium,
+	Sources:   []string{parser.SourceNginx},
+}
+
+type pathHit struct {
+	path string
+	time time.Time
+}
+
+// PathScanRule обнаруживает перебор каталогов (gobuster, dirb): много разных
+// несуществующих путей (ответы 4xx) с одного IP за окно
+type PathScanRule struct {
+	cfg         Config
+	hits        map[string][]pathHit
+	alerts      map[string]time.Time
+	lastCleanup time.Time
+}
+
+func NewPathScanRule(cfg Config) *PathScanRule {
+	return &PathScanRule{
+		cfg:    cfg,
+		hits:   make(map[string][]pathHit),
+		alerts: make(map[string]time.Time),
+	}
+}
+
+func init() {
+	Register("path_scan", pathScanDefaults, func(cfg Config) Rule { return NewPathScanRule(cfg) })
+}
+
+func (r *PathScanRule) Name() string {
+	return "path_scan"
+}
+
+func (r *PathScanRule) Sources() []string {
+	return r.cfg.Sources
+}
+
+func (r *PathScanRule) Reconfigure(cfg Config) {
+	r.cfg = cfg
+}
+
+func (r *PathScanRule) Check(entry parser.LogEntry, now time.Time) []parser.Alert {
+	// Учитываем только ответы 4xx на запросы, разобранные из nginx
+	log, ok := entry.(parser.NginxLog)
+	if !ok || !strings.HasPrefix(log.Status, "4") {
+		return nil
+	}
+
+	r.cleanup(now)
+
+	remoteAddr := log.RemoteAddr
+	r.hits[remoteAddr] = append(r.hits[remoteAddr], pathHit{path: log.Path, time: now})
+
+	// Очистка старых запросов
+	var recentHits []pathHit
+	for _, hit := range r.hits[remoteAddr] {
+		if now.Sub(hit.time) <= r.cfg.Window {
+			recentHits = append(recentHits, hit)
+		}
+	}
+	r.hits[remoteAddr] = recentHits
+
+	// Проверка уникальных путей
+	uniquePaths := make(map[string]bool)
+	var samples []string
+	for _, hit := range recentHits {
+		if uniquePaths[hit.path] {
+			continue
+		}
+		uniquePaths[hit.path] = true
+		if len(samples) < pathScanSamples {
+			samples = append(samples, hit.path)
+		}
+	}
+
+	if len(uniquePaths) >= r.cfg.Threshold {
+		if lastAlert, exists := r.alerts[remoteAddr]; !exists || now.Sub(lastAlert) > r.cfg.Cooldown {
+			r.alerts[remoteAddr] = now
+			delete(r.hits, remoteAddr)
+
+			return []parser.Alert{{
+				Type:       "path_scan",
+				Severity:   r.cfg.Severity,
+				Date:       now,
+				RemoteAddr: remoteAddr,
+				Action:     "scan",
+				Count:      len(uniquePaths),
+				Samples:    samples,
+			}}
+		}
+	}
+	return nil
+}
+
+// cleanup удаляет IP, от которых давно не было запросов; не чаще раза за окно
+func (r *PathScanRule) cleanup(now time.Time) {
+	if now.Sub(r.lastCleanup) <= r.cfg.Window {
+		return
+	}
+	r.lastCleanup = now
+
+	for remoteAddr, hits := range r.hits {
+		if len(hits) == 0 || now.Sub(hits[len(hits)-1].time) > r.cfg.Window {
+			delete(r.hits, remoteAddr)
+		}
+	}
+	CleanupOldAlerts(r.alerts, now, r.cfg.Cooldown)
+}
@@ -0,0 +1,152 @@
+package rules
+
+import (
+	"alertsystem/parser"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPathScan(t *testing.T) {
+	type hit struct {
+		addr, path, status string
+	}
+	// scan - count разных путей с ответом 404 с одного адреса
+	scan := func(addr string, count int) []hit {
+		var hits []hit
+		for i := 0; i < count; i++ {
+			hits = append(hits, hit{addr, fmt.Sprintf("/p%d", i), "404"})
+		}
+		return hits
+	}
+
+	tests := []struct {
+		name     string
+		cfg      Config
+		interval time.Duration
+		hits     []hit
+		want     []string // "адрес число_путей [примеры]"
+	}{
+		{
+			name: "distinct not found paths",
+			hits: scan("203.0.113.10", 3),
+			want: []string{"203.0.113.10 3 [/p0 /p1 /p2]"},
+		},
+		{
+			name: "same path repeated",
+			hits: []hit{
+				{"203.0.113.10", "/login", "404"},
+				{"203.0.113.10", "/login", "404"},
+				{"203.0.113.10", "/login", "404"},
+				{"203.0.113.10", "/login", "404"},
+			},
+		},
+		{
+			name: "only 4xx responses count",
+			hits: []hit{
+				{"203.0.113.10", "/a", "404"},
+				{"203.0.113.10", "/b", "200"},
+				{"203.0.113.10", "/c", "301"},
+				{"203.0.113.10", "/d", "500"},
+				{"203.0.113.10", "/e", "403"},
+				{"203.0.113.10", "/f", "401"},
+			},
+			want: []string{"203.0.113.10 3 [/a /e /f]"},
+		},
+		{
+			name: "different addresses",
+			hits: []hit{
+				{"203.0.113.10", "/a", "404"},
+				{"198.51.100.1", "/b", "404"},
+				{"192.0.2.1", "/c", "404"},
+			},
+		},
+		{
+			name:     "paths outside window",
+			interval: 40 * time.Second,
+			hits:     scan("203.0.113.10", 3),
+		},
+		{
+			name: "cooldown per address",
+			hits: append(scan("203.0.113.10", 6), scan("198.51.100.1", 3)...),
+			want: []string{
+				"203.0.113.10 3 [/p0 /p1 /p2]",
+				"198.51.100.1 3 [/p0 /p1 /p2]",
+			},
+		},
+		{
+			name: "samples limited",
+			cfg:  Config{Threshold: pathScanSamples + 2},
+			hits: scan("203.0.113.10", pathScanSamples+2),
+			want: []string{"203.0.113.10 12 [/p0 /p1 /p2 /p3 /p4 /p5 /p6 /p7 /p8 /p9]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			if cfg.Threshold == 0 {
+				cfg.Threshold = 3
+			}
+			rule := NewPathScanRule(cfg.withDefaults(pathScanDefaults))
+			interval := tt.interval
+			if interval == 0 {
+				interval = time.Second
+			}
+
+			var got []string
+			for i, h := range tt.hits {
+				log := parser.NginxLog{RemoteAddr: h.addr, Method: "GET", Path: h.path, Status: h.status}
+				for _, alert := range rule.Check(log, testNow.Add(time.Duration(i)*interval)) {
+					got = append(got, fmt.Sprintf("%s %d %v", alert.RemoteAddr, alert.Count, alert.Samples))
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("alerts = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPathScanCooldownExpires(t *testing.T) {
+	rule := NewPathScanRule(Config{Threshold: 2}.withDefaults(pathScanDefaults))
+	check := func(at time.Duration, path string) []parser.Alert {
+		return rule.Check(parser.NginxLog{RemoteAddr: "203.0.113.10", Path: path, Status: "404"}, testNow.Add(at))
+	}
+
+	check(0, "/a")
+	if alerts := check(time.Second, "/b"); len(alerts) != 1 {
+		t.Fatalf("got %d alerts, want 1", len(alerts))
+	}
+	check(4*time.Minute, "/c")
+	if alerts := check(4*time.Minute+time.Second, "/d"); len(alerts) != 0 {
+		t.Errorf("alert within cooldown: %+v", alerts)
+	}
+	check(5*time.Minute+2*time.Second, "/e")
+	if alerts := check(5*time.Minute+3*time.Second, "/f"); len(alerts) != 1 {
+		t.Errorf("got %d alerts after cooldown, want 1", len(alerts))
+	}
+
+	// Записи приложения не учитываются
+	if alerts := rule.Check(parser.WebServiceLog{RemoteAddr: "203.0.113.10", Status: "404"}, testNow); alerts != nil {
+		t.Errorf("web log raised %+v", alerts)
+	}
+}
+
+func TestSensitivePaths(t *testing.T) {
+	for _, pattern := range sensitivePaths {
+		// Путь запроса приводится к нижнему регистру, шаблон в другом регистре не сработает
+		if pattern != strings.ToLower(pattern) {
+			t.Errorf("pattern %q is not lower case", pattern)
+		}
+		if !strings.HasPrefix(pattern, "/") {
+			t.Errorf("pattern %q does not start with /", pattern)
+		}
+		path := strings.ToUpper(pattern) + "x"
+		if _, ok := detectSensitivePath(parser.NginxLog{Path: path}); !ok {
+			t.Errorf("path %q is not detected", path)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package rules
+
+import (
+	"alertsystem/parser"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Rule - общее описание правила детектирования
+type Rule interface {
+	// Name возвращает уникальное имя правила
+	Name() string
+	// Sources возвращает список источников логов, к которым применяется правило
+	Sources() []string
+	// Check проверяет запись и возвращает сработавшие алерты
+	Check(entry parser.LogEntry, now time.Time) []parser.Alert
+}
+
+// Reconfigurable реализуют правила, способные сменить параметры
+// без потери накопленного состояния (скользящих окон, времени последних алертов)
+type Reconfigurable interface {
+	Reconfigure(cfg Config)
+}
+
+// AlertObserver реализуют правила, которые учитывают алерты других правил.
+// Алерты передаются после проверки записи всеми правилами.
+type AlertObserver interface {
+	ObserveAlert(alert parser.Alert)
+}
+
+// Factory создает правило с уже дополненной значениями по умолчанию конфигурацией
+type Factory func(cfg Config) Rule
+
+type registration struct {
+	defaults Config
+	factory  Factory
+}
+
+var factories = make(map[string]registration)
+
+// Register добавляет правило в реестр доступных правил
+func Register(name string, defaults Config, factory Factory) {
+	if _, exists := factories[name]; exists {
+		panic("rules: duplicate rule " + name)
+	}
+	factories[name] = registration{defaults: defaults, factory: factory}
+}
+
+// Available возвращает имена всех зарегистрированных правил
+func Available() []string {
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DefaultConfig возвращает настройки правила по умолчанию
+func DefaultConfig(name string) (Config, bool) {
+	reg, ok := factories[name]
+	return reg.defaults, ok
+}
+
+type Registry struct {
+	mu    sync.Mutex
+	rules []Rule
+}
+
+// NewRegistry создает реестр из включенных правил.
+// Правила, отсутствующие в configs, создаются с настройками по умолчанию.
+func NewRegistry(configs map[string]Config) (*Registry, error) {
+	r := &Registry{}
+	if err := r.Reload(configs); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload атомарно применяет новую конфигурацию. Уже работающие правила с тем же
+// именем получают новые параметры и сохраняют состояние, выключенные правила удаляются.
+// При ошибке в конфигурации текущий набор правил не меняется.
+func (r *Registry) Reload(configs map[string]Config) error {
+	if err := ValidateConfigs(configs); err != nil {
+		return err
+	}
+
+	type pending struct {
+		name string
+		reg  registration
+		cfg  Config
+	}
+	var enabled []pending
+	for _, name := range Available() {
+		reg := factories[name]
+		cfg := configs[name].withDefaults(reg.defaults)
+		if !cfg.IsEnabled() {
+			continue
+		}
+		if err := cfg.validate(); err != nil {
+			return fmt.Errorf("rule %q: %w", name, err)
+		}
+		enabled = append(enabled, pending{name: name, reg: reg, cfg: cfg})
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current := make(map[string]Rule, len(r.rules))
+	for _, rule := range r.rules {
+		current[rule.Name()] = rule
+	}
+
+	rules := make([]Rule, 0, len(enabled))
+	for _, p := range enabled {
+		if existing, ok := current[p.name]; ok {
+			if rc, ok := existing.(Reconfigurable); ok {
+				rc.Reconfigure(p.cfg)
+				rules = append(rules, existing)
+				continue
+			}
+		}
+		rules = append(rules, p.reg.factory(p.cfg))
+	}
+	r.rules = rules
+	return nil
+}
+
+func (r *Registry) Add(rule Rule) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rules = append(r.rules, rule)
+}
+
+func (r *Registry) Rules() []Rule {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Rule(nil), r.rules...)
+}
+
+// Check прогоняет запись через все правила, подходящие по источнику
+func (r *Registry) Check(entry parser.LogEntry, now time.Time) []parser.Alert {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var alerts []parser.Alert
+	for _, rule := range r.rules {
+		if !appliesTo(rule, entry.Source()) {
+			continue
+		}
+		alerts = append(alerts, rule.Check(entry, now)...)
+	}
+
+	for _, rule := range r.rules {
+		observer, ok := rule.(AlertObserver)
+		if !ok {
+			continue
+		}
+		for _, alert := range alerts {
+			observer.ObserveAlert(alert)
+		}
+	}
+	return alerts
+}
+
+func appliesTo(rule Rule, source string) bool {
+	for _, s := range rule.Sources() {
+		if s == source {
+			return true
+		}
+	}
+	return false
+}
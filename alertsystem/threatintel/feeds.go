@@ -0,0 +1,281 @@
+// Package threatintel сверяет адреса клиентов с локальными списками
+// репутации: простыми списками IP/CIDR, списками выходных узлов Tor,
+// netset-файлами FireHOL. Имя списка - имя файла без расширения.
+package threatintel
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Config - каталог со списками; пустой путь отключает проверку
+type Config struct {
+	Dir string `yaml:"dir"`
+	// ReloadInterval - как часто проверять изменения файлов в каталоге; 0 отключает
+	ReloadInterval time.Duration `yaml:"reload_interval"`
+}
+
+func DefaultConfig() Config {
+	return Config{ReloadInterval: 1 * time.Minute}
+}
+
+func (c Config) Validate() error {
+	if c.ReloadInterval < 0 {
+		return fmt.Errorf("reload_interval must not be negative, got %s", c.ReloadInterval)
+	}
+	return nil
+}
+
+// Служебные строки формата exit-addresses проекта Tor; адрес - в строке ExitAddress
+var torKeywords = map[string]bool{
+	"ExitNode":   true,
+	"Published":  true,
+	"LastStatus": true,
+}
+
+// Feeds - списки из каталога. При изменении файлов индекс строится заново
+// и подменяется целиком, поиск при этом не блокируется.
+type Feeds struct {
+	cfg       Config
+	index     atomic.Pointer[Index]
+	mu        sync.Mutex // перезагрузки
+	signature string
+	missing   bool // об отсутствии каталога уже сообщено
+}
+
+// Open загружает списки из каталога. Отсутствующий каталог не ошибка:
+// списки загрузятся при следующей проверке после его появления.
+func Open(cfg Config) (*Feeds, error) {
+	f := &Feeds{cfg: cfg}
+	if err := f.Reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Lookup возвращает имена списков, в которые входит адрес
+func (f *Feeds) Lookup(remoteAddr string) []string {
+	if f == nil {
+		return nil
+	}
+	return f.index.Load().Lookup(remoteAddr)
+}
+
+// Reload загружает списки заново, если файлы в каталоге изменились.
+// При ошибке продолжают работать прежние списки.
+func (f *Feeds) Reload() error {
+	if f == nil || f.cfg.Dir == "" {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	files, signature, err := listFeeds(f.cfg.Dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		if !f.missing {
+			log.Printf("Threat intel directory %s not found, feeds will be loaded when it appears", f.cfg.Dir)
+			f.missing = true
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	f.missing = false
+	if signature == f.signature {
+		return nil
+	}
+
+	index, err := loadFeeds(files)
+	if err != nil {
+		return err
+	}
+	f.index.Store(index)
+	f.signature = signature
+	log.Printf("Loaded %d threat intel feeds from %s: %d networks", len(index.feeds), f.cfg.Dir, index.networks)
+	return nil
+}
+
+// Watch проверяет каталог каждые ReloadInterval до отмены ctx
+func (f *Feeds) Watch(ctx context.Context) {
+	if f == nil || f.cfg.Dir == "" || f.cfg.ReloadInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(f.cfg.ReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := f.Reload(); err != nil {
+				log.Printf("Threat intel reload failed, keeping previous feeds: %v", err)
+			}
+		}
+	}
+}
+
+// listFeeds возвращает файлы списков и их сводку (имя, размер, время изменения)
+// для определения изменений
+func listFeeds(dir string) ([]string, string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read threat intel directory: %w", err)
+	}
+
+	var files []string
+	var signature strings.Builder
+	for _, entry := range entries {
+		// Скрытые файлы (.gitkeep, временные файлы загрузки) пропускаются
+		if strings.HasPrefix(entry.Name(), ".") || !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to stat threat intel feed: %w", err)
+		}
+		path := filepath.Join(dir, entry.Name())
+		files = append(files, path)
+		fmt.Fprintf(&signature, "%s %d %d\n", entry.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+	return files, signature.String(), nil
+}
+
+func loadFeeds(files []string) (*Index, error) {
+	var names []string
+	numbers := make(map[string]int)
+	var intervals []interval
+	for _, path := range files {
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		feed, ok := numbers[name]
+		if !ok {
+			feed = len(names)
+			numbers[name] = feed
+			names = append(names, name)
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open threat intel feed: %w", err)
+		}
+		parsed, skipped, err := parseFeed(file, feed)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read threat intel feed %s: %w", path, err)
+		}
+		if skipped > 0 {
+			log.Printf("Threat intel feed %s: skipped %d unparsable lines", path, skipped)
+		}
+		intervals = append(intervals, parsed...)
+	}
+
+	// Имена по алфавиту, чтобы совпадения перечислялись в одном порядке
+	order := make([]int, len(names))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return names[order[i]] < names[order[j]] })
+	renumber := make([]int, len(names))
+	sorted := make([]string, len(names))
+	for i, feed := range order {
+		renumber[feed] = i
+		sorted[i] = names[feed]
+	}
+	for i := range intervals {
+		intervals[i].feed = renumber[intervals[i].feed]
+	}
+
+	return buildIndex(sorted, intervals), nil
+}
+
+// parseFeed читает список: по адресу, сети или диапазону "начало-конец" в строке,
+// комментарии начинаются с # или ;
+func parseFeed(r io.Reader, feed int) ([]interval, int, error) {
+	var intervals []interval
+	var skipped int
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		line, _, _ = strings.Cut(line, ";")
+		fields := strings.Fields(line)
+		if len(fields) == 0 || torKeywords[fields[0]] {
+			continue
+		}
+		token := fields[0]
+		if token == "ExitAddress" && len(fields) > 1 {
+			token = fields[1]
+		}
+
+		start, end, ok := parseNetwork(token)
+		if !ok {
+			skipped++
+			continue
+		}
+		intervals = append(intervals, interval{start: start, end: end, feed: feed})
+	}
+	return intervals, skipped, scanner.Err()
+}
+
+// parseNetwork разбирает адрес, сеть CIDR или диапазон в границы диапазона
+func parseNetwork(token string) (netip.Addr, netip.Addr, bool) {
+	if from, to, ok := strings.Cut(token, "-"); ok {
+		start, err1 := netip.ParseAddr(from)
+		end, err2 := netip.ParseAddr(to)
+		start, end = start.Unmap(), end.Unmap()
+		if err1 != nil || err2 != nil || start.Is4() != end.Is4() || end.Less(start) {
+			return netip.Addr{}, netip.Addr{}, false
+		}
+		return start, end, true
+	}
+
+	if strings.Contains(token, "/") {
+		prefix, err := netip.ParsePrefix(token)
+		if err != nil {
+			return netip.Addr{}, netip.Addr{}, false
+		}
+		// Сети вида ::ffff:0:0/96 приводятся к IPv4, как и адреса при поиске
+		if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+		prefix = prefix.Masked()
+		return prefix.Addr(), prefixEnd(prefix), true
+	}
+
+	addr, err := netip.ParseAddr(token)
+	if err != nil {
+		return netip.Addr{}, netip.Addr{}, false
+	}
+	addr = addr.Unmap().WithZone("")
+	return addr, addr, true
+}
+
+// prefixEnd - последний адрес сети
+func prefixEnd(prefix netip.Prefix) netip.Addr {
+	bytes := prefix.Addr().As16()
+	offset := 0
+	if prefix.Addr().Is4() {
+		offset = 96
+	}
+	for bit := offset + prefix.Bits(); bit < 128; bit++ {
+		bytes[bit/8] |= 1 << (7 - bit%8)
+	}
+	end := netip.AddrFrom16(bytes)
+	if prefix.Addr().Is4() {
+		end = end.Unmap()
+	}
+	return end
+}
@@ -0,0 +1,67 @@
+package threatintel
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseNetwork(t *testing.T) {
+	tests := []struct {
+		token      string
+		start, end string
+		ok         bool
+	}{
+		{"192.0.2.1", "192.0.2.1", "192.0.2.1", true},
+		{"192.0.2.0/24", "192.0.2.0", "192.0.2.255", true},
+		{"192.0.2.77/24", "192.0.2.0", "192.0.2.255", true},
+		{"0.0.0.0/0", "0.0.0.0", "255.255.255.255", true},
+		{"::ffff:192.0.2.0/120", "192.0.2.0", "192.0.2.255", true},
+		{"::ffff:192.0.2.1", "192.0.2.1", "192.0.2.1", true},
+		{"2001:db8::/32", "2001:db8::", "2001:db8:ffff:ffff:ffff:ffff:ffff:ffff", true},
+		{"192.0.2.10-192.0.2.20", "192.0.2.10", "192.0.2.20", true},
+		{"192.0.2.20-192.0.2.10", "", "", false},
+		{"192.0.2.1-2001:db8::1", "", "", false},
+		{"192.0.2.0/33", "", "", false},
+		{"example.com", "", "", false},
+	}
+	for _, tt := range tests {
+		start, end, ok := parseNetwork(tt.token)
+		if ok != tt.ok {
+			t.Errorf("parseNetwork(%q) ok = %v, want %v", tt.token, ok, tt.ok)
+			continue
+		}
+		if ok && (start.String() != tt.start || end.String() != tt.end) {
+			t.Errorf("parseNetwork(%q) = %s-%s, want %s-%s", tt.token, start, end, tt.start, tt.end)
+		}
+	}
+}
+
+func TestParseFeed(t *testing.T) {
+	feed := `# FireHOL netset
+; Spamhaus DROP
+192.0.2.0/24 ; SBL123
+198.51.100.7   # comment
+ExitNode 0011BD2485AD45D984EC4159C88FC066E5E3300E
+Published 2026-10-18 08:00:00
+LastStatus 2026-10-18 09:00:00
+ExitAddress 203.0.113.5 2026-10-18 09:01:00
+
+garbage line
+`
+	intervals, skipped, err := parseFeed(strings.NewReader(feed), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if skipped != 1 {
+		t.Errorf("skipped = %d, want 1", skipped)
+	}
+	want := []string{"192.0.2.0-192.0.2.255", "198.51.100.7-198.51.100.7", "203.0.113.5-203.0.113.5"}
+	if len(intervals) != len(want) {
+		t.Fatalf("got %d intervals, want %d", len(intervals), len(want))
+	}
+	for i, in := range intervals {
+		if got := in.start.String() + "-" + in.end.String(); got != want[i] || in.feed != 3 {
+			t.Errorf("interval %d = %s (feed %d), want %s (feed 3)", i, got, in.feed, want[i])
+		}
+	}
+}
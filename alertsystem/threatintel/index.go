@@ -0,0 +1,158 @@
+package threatintel
+
+import (
+	"net/netip"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Диапазон адресов из списка feed; границы включительно
+type interval struct {
+	start netip.Addr
+	end   netip.Addr
+	feed  int
+}
+
+// segment - участок адресов, в котором одинаков набор совпавших списков.
+// Участки не пересекаются и отсортированы, поэтому поиск - двоичный.
+type segment struct {
+	start netip.Addr
+	end   netip.Addr
+	feeds []string
+}
+
+// Index - неизменяемый индекс списков для поиска адреса за O(log n).
+// Пересекающиеся сети разных списков разбиваются на непересекающиеся участки
+// при построении, а не при поиске.
+type Index struct {
+	segments []segment
+	feeds    []string
+	networks int
+}
+
+// buildIndex строит индекс из диапазонов; feeds - имена списков по номерам
+func buildIndex(feeds []string, intervals []interval) *Index {
+	// Начало диапазона добавляет список, адрес после конца - убирает
+	type event struct {
+		addr  netip.Addr
+		feed  int
+		delta int
+	}
+	events := make([]event, 0, 2*len(intervals))
+	for _, in := range intervals {
+		events = append(events, event{addr: in.start, feed: in.feed, delta: 1})
+		// IPv4 в порядке сортировки идут перед IPv6: диапазон до конца IPv4
+		// закрывается на первом адресе IPv6, до конца IPv6 - не закрывается
+		next := in.end.Next()
+		if !next.IsValid() && in.end.Is4() {
+			next = netip.IPv6Unspecified()
+		}
+		if next.IsValid() {
+			events = append(events, event{addr: next, feed: in.feed, delta: -1})
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].addr.Less(events[j].addr)
+	})
+
+	index := &Index{feeds: feeds, networks: len(intervals)}
+	active := make([]int, len(feeds)) // число открытых диапазонов по спискам
+	sets := make(map[string][]string) // одинаковые наборы списков хранятся один раз
+	open := false
+	for i := 0; i < len(events); {
+		addr := events[i].addr
+		for ; i < len(events) && events[i].addr == addr; i++ {
+			active[events[i].feed] += events[i].delta
+		}
+
+		var names []string
+		for feed, count := range active {
+			if count > 0 {
+				names = append(names, feeds[feed])
+			}
+		}
+		if len(names) > 0 {
+			key := strings.Join(names, "\x00")
+			if set, ok := sets[key]; ok {
+				names = set
+			} else {
+				sets[key] = names
+			}
+		}
+
+		if open {
+			last := &index.segments[len(index.segments)-1]
+			// Набор списков не изменился - участок продолжается
+			if len(names) > 0 && &last.feeds[0] == &names[0] && last.start.Is4() == addr.Is4() {
+				continue
+			}
+			// Закрываем участок перед текущим адресом; участок, открытый
+			// до конца IPv4, уже заканчивается последним адресом IPv4
+			if prev := addr.Prev(); prev.IsValid() {
+				last.end = prev
+			}
+		}
+
+		open = len(names) > 0
+		if !open {
+			continue
+		}
+		index.segments = append(index.segments, segment{start: addr, end: lastAddr(addr), feeds: names})
+	}
+	return index
+}
+
+// lastAddr - последний адрес семейства (IPv4 или IPv6), которому принадлежит addr
+func lastAddr(addr netip.Addr) netip.Addr {
+	if addr.Is4() {
+		return netip.AddrFrom4([4]byte{255, 255, 255, 255})
+	}
+	var max [16]byte
+	for i := range max {
+		max[i] = 0xff
+	}
+	return netip.AddrFrom16(max)
+}
+
+// Lookup возвращает имена списков, в которые входит адрес.
+// Результат общий для всех вызовов и не должен изменяться.
+func (x *Index) Lookup(remoteAddr string) []string {
+	if x == nil || len(x.segments) == 0 {
+		return nil
+	}
+	addr, err := netip.ParseAddr(remoteAddr)
+	if err != nil {
+		return nil
+	}
+	addr = addr.Unmap().WithZone("")
+
+	// Первый участок, начинающийся после адреса; искомый - перед ним
+	i := sort.Search(len(x.segments), func(i int) bool {
+		return addr.Less(x.segments[i].start)
+	})
+	if i == 0 {
+		return nil
+	}
+	seg := x.segments[i-1]
+	if seg.end.Less(addr) {
+		return nil
+	}
+	return seg.feeds
+}
+
+// Feeds возвращает имена загруженных списков
+func (x *Index) Feeds() []string {
+	if x == nil {
+		return nil
+	}
+	return slices.Clone(x.feeds)
+}
+
+// Networks возвращает общее число сетей и адресов во всех списках
+func (x *Index) Networks() int {
+	if x == nil {
+		return 0
+	}
+	return x.networks
+}
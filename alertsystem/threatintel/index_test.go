@@ -0,0 +1,127 @@
+package threatintel
+
+import (
+	"math/rand"
+	"net/netip"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// testIndex строит индекс из списков вида имя -> сети, как их читает loadFeeds
+func testIndex(t *testing.T, feeds map[string][]string) *Index {
+	t.Helper()
+	var names []string
+	for name := range feeds {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var intervals []interval
+	for feed, name := range names {
+		parsed, skipped, err := parseFeed(strings.NewReader(strings.Join(feeds[name], "\n")), feed)
+		if err != nil || skipped > 0 {
+			t.Fatalf("parseFeed(%s): skipped %d, err %v", name, skipped, err)
+		}
+		intervals = append(intervals, parsed...)
+	}
+	return buildIndex(names, intervals)
+}
+
+func TestIndexLookup(t *testing.T) {
+	index := testIndex(t, map[string][]string{
+		"firehol":  {"10.0.0.0/8", "192.0.2.0/24", "2001:db8::/32"},
+		"spamhaus": {"10.1.0.0/16", "192.0.2.128/25"},
+		"tor":      {"10.1.2.3", "198.51.100.10-198.51.100.20", "255.255.255.255"},
+		"edge":     {"240.0.0.0/4", "ffff::/16"},
+	})
+
+	tests := []struct {
+		addr  string
+		feeds []string
+	}{
+		{"9.255.255.255", nil},
+		{"10.0.0.0", []string{"firehol"}},
+		{"10.0.255.255", []string{"firehol"}},
+		{"10.1.0.0", []string{"firehol", "spamhaus"}},
+		{"10.1.2.3", []string{"firehol", "spamhaus", "tor"}},
+		{"10.1.2.4", []string{"firehol", "spamhaus"}},
+		{"10.2.0.0", []string{"firehol"}},
+		{"10.255.255.255", []string{"firehol"}},
+		{"11.0.0.0", nil},
+		{"192.0.2.127", []string{"firehol"}},
+		{"192.0.2.128", []string{"firehol", "spamhaus"}},
+		{"192.0.2.255", []string{"firehol", "spamhaus"}},
+		{"198.51.100.9", nil},
+		{"198.51.100.10", []string{"tor"}},
+		{"198.51.100.20", []string{"tor"}},
+		{"198.51.100.21", nil},
+		{"255.255.255.254", []string{"edge"}},
+		{"255.255.255.255", []string{"edge", "tor"}},
+		// Диапазон до конца IPv4 не продолжается в IPv6
+		{"::", nil},
+		{"::ffff:10.1.2.3", []string{"firehol", "spamhaus", "tor"}},
+		{"2001:db8::1", []string{"firehol"}},
+		{"2001:db9::", nil},
+		{"ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", []string{"edge"}},
+		{"not an address", nil},
+	}
+	for _, tt := range tests {
+		if got := index.Lookup(tt.addr); !slices.Equal(got, tt.feeds) {
+			t.Errorf("Lookup(%q) = %v, want %v", tt.addr, got, tt.feeds)
+		}
+	}
+
+	if got := index.Networks(); got != 10 {
+		t.Errorf("Networks() = %d, want 10", got)
+	}
+}
+
+func TestIndexEmpty(t *testing.T) {
+	var nilIndex *Index
+	for _, index := range []*Index{nilIndex, buildIndex(nil, nil)} {
+		if got := index.Lookup("10.0.0.1"); got != nil {
+			t.Errorf("Lookup on empty index = %v", got)
+		}
+	}
+}
+
+// TestIndexRandom сравнивает индекс с перебором всех диапазонов
+func TestIndexRandom(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	names := []string{"a", "b", "c", "d"}
+	var intervals []interval
+	for range 300 {
+		addr := netip.AddrFrom4([4]byte{10, byte(rnd.Intn(4)), byte(rnd.Intn(256)), byte(rnd.Intn(256))})
+		prefix := netip.PrefixFrom(addr, 16+rnd.Intn(17)).Masked()
+		intervals = append(intervals, interval{start: prefix.Addr(), end: prefixEnd(prefix), feed: rnd.Intn(len(names))})
+	}
+	index := buildIndex(names, intervals)
+
+	for range 20000 {
+		addr := netip.AddrFrom4([4]byte{10, byte(rnd.Intn(5)), byte(rnd.Intn(256)), byte(rnd.Intn(256))})
+		var want []string
+		for feed, name := range names {
+			for _, in := range intervals {
+				if in.feed == feed && !addr.Less(in.start) && !in.end.Less(addr) {
+					want = append(want, name)
+					break
+				}
+			}
+		}
+		if got := index.Lookup(addr.String()); !slices.Equal(got, want) {
+			t.Fatalf("Lookup(%s) = %v, want %v", addr, got, want)
+		}
+	}
+
+	// Соседние участки с одинаковым набором списков объединяются
+	for i := 1; i < len(index.segments); i++ {
+		prev, seg := index.segments[i-1], index.segments[i]
+		if !prev.end.Less(seg.start) {
+			t.Fatalf("segments %v and %v overlap", prev, seg)
+		}
+		if prev.end.Next() == seg.start && slices.Equal(prev.feeds, seg.feeds) {
+			t.Fatalf("adjacent segments %v and %v were not merged", prev, seg)
+		}
+	}
+}
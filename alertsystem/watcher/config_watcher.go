@@ -0,0 +1,64 @@
+package watcher
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// ConfigWatcher следит за файлом целиком и вызывает OnChange после каждого изменения.
+// Наблюдение ведется за каталогом, так как редакторы и ConfigMap заменяют файл
+// через rename, после чего наблюдение за самим файлом теряется.
+type ConfigWatcher struct {
+	Path     string
+	OnChange func()
+	state    struct {
+		modTime time.Time
+		size    int64
+	}
+}
+
+func NewConfigWatcher(path string, onChange func()) *ConfigWatcher {
+	cw := &ConfigWatcher{
+		Path:     filepath.Clean(path),
+		OnChange: onChange,
+	}
+	// Запоминаем текущее состояние, чтобы не перечитывать уже загруженный файл
+	if fileInfo, err := os.Stat(cw.Path); err == nil {
+		cw.state.modTime = fileInfo.ModTime()
+		cw.state.size = fileInfo.Size()
+	}
+	return cw
+}
+
+// Watch следит за файлом до отмены ctx
+func (cw *ConfigWatcher) Watch(ctx context.Context) {
+	log.Printf("Watching config: %s", cw.Path)
+
+	watchLoop(ctx, []string{filepath.Dir(cw.Path)}, func(event fsnotify.Event) {
+		if filepath.Clean(event.Name) == cw.Path &&
+			event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+			cw.processChanges()
+		}
+	}, cw.processChanges)
+}
+
+func (cw *ConfigWatcher) processChanges() {
+	fileInfo, err := os.Stat(cw.Path)
+	if err != nil {
+		// Файл может временно отсутствовать во время замены
+		return
+	}
+
+	if fileInfo.ModTime().Equal(cw.state.modTime) && fileInfo.Size() == cw.state.size {
+		return
+	}
+	cw.state.modTime = fileInfo.ModTime()
+	cw.state.size = fileInfo.Size()
+
+	cw.OnChange()
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package watcher
+
+import "os"
+
+// На платформах без inode сравнение файлов идет только по отпечатку
+func inode(fileInfo os.FileInfo) uint64 {
+	return 0
+}
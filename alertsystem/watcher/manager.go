@@ -0,0 +1,144 @@
+package watcher
+
+import (
+	"context"
+	"log"
+	"path/filepath"
+	"sort"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Source - группа файлов, заданных шаблонами, с общим обработчиком строк
+type Source struct {
+	Name string
+	// Patterns - шаблоны путей (filepath.Match); шаблон допустим только в имени файла
+	Patterns []string
+	OnLine   func(string)
+}
+
+// Manager следит за всеми файлами, подходящими под шаблоны источников,
+// включая файлы, созданные после запуска
+type Manager struct {
+	// State - хранилище позиций чтения; nil отключает сохранение между перезапусками
+	State *StateStore
+	// StartFromEnd - начинать с конца файлов, найденных при запуске без сохраненной позиции
+	StartFromEnd bool
+	sources      []Source
+	files        map[string]*FileWatcher
+}
+
+func NewManager(state *StateStore, startFromEnd bool) *Manager {
+	return &Manager{
+		State:        state,
+		StartFromEnd: startFromEnd,
+		files:        make(map[string]*FileWatcher),
+	}
+}
+
+func (m *Manager) Add(source Source) {
+	m.sources = append(m.sources, source)
+}
+
+// Watch обрабатывает все источники в одной горутине до отмены ctx
+func (m *Manager) Watch(ctx context.Context) {
+	m.scan(true)
+
+	dirs := make(map[string]bool)
+	for _, source := range m.sources {
+		for _, pattern := range source.Patterns {
+			dirs[filepath.Dir(pattern)] = true
+		}
+	}
+	targets := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		targets = append(targets, dir)
+		log.Printf("Watching directory: %s", dir)
+	}
+	sort.Strings(targets)
+
+	watchLoop(ctx, targets, m.handle, m.poll)
+}
+
+func (m *Manager) handle(event fsnotify.Event) {
+	if event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+		return
+	}
+
+	path := filepath.Clean(event.Name)
+	if fw, ok := m.files[path]; ok {
+		fw.processChanges()
+		return
+	}
+
+	if event.Op&fsnotify.Create != 0 {
+		if source, ok := m.match(path); ok {
+			m.startFile(path, source, false).processChanges()
+		}
+	}
+}
+
+// poll перечитывает все файлы и ищет новые - на случай пропущенных событий
+func (m *Manager) poll() {
+	m.scan(false)
+	for _, path := range m.sortedPaths() {
+		m.files[path].processChanges()
+	}
+}
+
+// scan находит файлы по шаблонам и начинает следить за новыми.
+// Файлы, найденные при запуске, подчиняются StartFromEnd;
+// появившиеся позже всегда читаются с начала.
+func (m *Manager) scan(initial bool) {
+	for _, source := range m.sources {
+		for _, pattern := range source.Patterns {
+			matches, err := filepath.Glob(pattern)
+			if err != nil {
+				log.Printf("Bad pattern %q: %v", pattern, err)
+				continue
+			}
+			for _, path := range matches {
+				path = filepath.Clean(path)
+				if _, ok := m.files[path]; ok {
+					continue
+				}
+				fw := m.startFile(path, source, initial)
+				if initial {
+					fw.processChanges()
+				}
+			}
+		}
+	}
+}
+
+func (m *Manager) startFile(path string, source Source, initial bool) *FileWatcher {
+	fw := New(path, source.OnLine)
+	fw.State = m.State
+	fw.StartFromEnd = initial && m.StartFromEnd
+	fw.restore()
+	m.files[fw.Path] = fw
+
+	log.Printf("Watching file: %s (source %s)", fw.Path, source.Name)
+	return fw
+}
+
+// match находит источник, к шаблонам которого подходит путь
+func (m *Manager) match(path string) (Source, bool) {
+	for _, source := range m.sources {
+		for _, pattern := range source.Patterns {
+			if ok, _ := filepath.Match(filepath.Clean(pattern), path); ok {
+				return source, true
+			}
+		}
+	}
+	return Source{}, false
+}
+
+func (m *Manager) sortedPaths() []string {
+	paths := make([]string, 0, len(m.files))
+	for path := range m.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,83 @@
+package watcher
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+// rotatedCandidates возвращает возможные имена файла после ротации logrotate,
+// как без сжатия, так и со сжатием
+func rotatedCandidates(path string) []string {
+	return []string{path + ".1", path + ".1.gz"}
+}
+
+// catchUpRotated находит ротированную копию файла, к которой относится
+// сохраненная позиция, и дочитывает ее до конца. Возвращает false,
+// если подходящего файла нет.
+func (fw *FileWatcher) catchUpRotated(saved FileState) bool {
+	if saved.FingerprintSize == 0 {
+		// Без отпечатка нельзя убедиться, что копия та же
+		return false
+	}
+	for _, candidate := range rotatedCandidates(fw.Path) {
+		if fw.catchUpFile(candidate, saved) {
+			return true
+		}
+	}
+	return false
+}
+
+func (fw *FileWatcher) catchUpFile(path string, saved FileState) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			log.Printf("Failed to open rotated file %s: %v", path, err)
+			return false
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	// Сравниваем отпечаток начала файла с сохраненным
+	head := make([]byte, saved.FingerprintSize)
+	if _, err := io.ReadFull(r, head); err != nil || fingerprintBytes(head) != saved.Fingerprint {
+		return false
+	}
+
+	// Пропускаем уже прочитанное (в сжатом файле переход к позиции возможен только чтением)
+	r = io.MultiReader(bytes.NewReader(head), r)
+	if _, err := io.CopyN(io.Discard, r, saved.Offset); err != nil {
+		log.Printf("Failed to skip to offset %d in %s: %v", saved.Offset, path, err)
+		return false
+	}
+
+	log.Printf("Catching up %s from rotated file %s at offset %d", fw.Path, path, saved.Offset)
+
+	// Файл больше не пишется, поэтому последняя строка без перевода строки тоже выдается
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			fw.OnChange(strings.TrimRight(line, "\r\n"))
+		}
+		if err != nil {
+			if err != io.EOF {
+				log.Printf("Read error in rotated file %s: %v", path, err)
+			}
+			break
+		}
+	}
+	return true
+}
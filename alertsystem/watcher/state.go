@@ -0,0 +1,106 @@
+package watcher
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Сколько байт от начала файла используется для отпечатка
+const fingerprintSize = 1024
+
+// FileState - позиция чтения файла, сохраняемая между перезапусками.
+// Inode и отпечаток начала файла позволяют понять, что на месте
+// файла теперь другой файл и сохраненная позиция к нему не относится.
+type FileState struct {
+	Offset          int64  `json:"offset"`
+	Inode           uint64 `json:"inode"`
+	Fingerprint     string `json:"fingerprint"`
+	FingerprintSize int64  `json:"fingerprint_size"`
+}
+
+// StateStore хранит позиции чтения всех наблюдаемых файлов в одном JSON-файле
+type StateStore struct {
+	path  string
+	mu    sync.Mutex
+	files map[string]FileState
+}
+
+// OpenStateStore загружает сохраненное состояние; отсутствие файла не является ошибкой
+func OpenStateStore(path string) (*StateStore, error) {
+	s := &StateStore{
+		path:  path,
+		files: make(map[string]FileState),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read watcher state: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.files); err != nil {
+		return nil, fmt.Errorf("failed to parse watcher state %s: %w", path, err)
+	}
+	return s, nil
+}
+
+func (s *StateStore) Get(file string) (FileState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.files[file]
+	return state, ok
+}
+
+// Set сохраняет позицию файла и сразу записывает состояние на диск
+func (s *StateStore) Set(file string, state FileState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[file] = state
+	return s.save()
+}
+
+// save записывает состояние через временный файл, чтобы при сбое
+// не остаться с наполовину записанным JSON
+func (s *StateStore) save() error {
+	data, err := json.MarshalIndent(s.files, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write watcher state: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to replace watcher state: %w", err)
+	}
+	return nil
+}
+
+// fingerprint вычисляет хеш первых size байт файла
+func fingerprint(file *os.File, size int64) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, io.NewSectionReader(file, 0, size)); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// fingerprintBytes вычисляет тот же хеш для уже прочитанного начала файла
+func fingerprintBytes(head []byte) string {
+	sum := sha256.Sum256(head)
+	return hex.EncodeToString(sum[:])
+}
@@ -2,100 +2,297 @@ package watcher
 
 import (
 	"bufio"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
 )
 
+const pollInterval = 5 * time.Second
+
 type FileWatcher struct {
 	Path     string
 	OnChange func(string)
-	state    struct {
-		pos     int64
-		modTime time.Time
+	// State - хранилище позиций чтения; nil отключает сохранение между перезапусками
+	State *StateStore
+	// StartFromEnd - начинать с конца файла, если сохраненной позиции нет
+	StartFromEnd bool
+	// file остается открытым между проверками: после переименования при ротации
+	// через него дочитывается хвост старого файла
+	file  *os.File
+	state struct {
+		pos             int64
+		fingerprint     string
+		fingerprintSize int64
+		saved           FileState
 	}
 }
 
 func New(path string, onChange func(string)) *FileWatcher {
 	return &FileWatcher{
-		Path:     path,
+		Path:     filepath.Clean(path),
 		OnChange: onChange,
 	}
 }
 
-func (fw *FileWatcher) Watch() {
+// Watch следит за файлом до отмены ctx
+func (fw *FileWatcher) Watch(ctx context.Context) {
+	fw.restore()
+	log.Printf("Watching file: %s", fw.Path)
+
+	// Наблюдаем за каталогом: при ротации файл переименовывается и создается заново
+	watchLoop(ctx, []string{filepath.Dir(fw.Path)}, func(event fsnotify.Event) {
+		if filepath.Clean(event.Name) == fw.Path &&
+			event.Op&(fsnotify.Write|fsnotify.Create) != 0 {
+			fw.processChanges()
+		}
+	}, fw.processChanges)
+}
+
+// watchLoop подписывается на события targets и передает их в handle,
+// а также периодически вызывает poll - на случай пропущенных событий.
+// Возвращается после отмены ctx, не прерывая обработку текущего события.
+func watchLoop(ctx context.Context, targets []string, handle func(fsnotify.Event), poll func()) {
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer watcher.Close()
 
-	err = watcher.Add(fw.Path)
-	if err != nil {
-		log.Fatal(err)
+	for _, target := range targets {
+		// Без подписки изменения все равно будут найдены периодическим опросом
+		if err := watcher.Add(target); err != nil {
+			log.Printf("Failed to watch %s: %v", target, err)
+		}
 	}
 
-	log.Printf("Watching file: %s", fw.Path)
-
-	ticker := time.NewTicker(5 * time.Second)
+	ticker := time.NewTicker(pollInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
+		case <-ctx.Done():
+			return
 		case event, ok := <-watcher.Events:
 			if !ok {
 				return
 			}
-			if event.Op&fsnotify.Write == fsnotify.Write {
-				fw.processChanges()
-			}
+			handle(event)
 		case err, ok := <-watcher.Errors:
 			if !ok {
 				return
 			}
 			log.Printf("Watcher error: %v", err)
 		case <-ticker.C:
-			fw.processChanges()
+			poll()
 		}
 	}
 }
 
-func (fw *FileWatcher) processChanges() {
-	fileInfo, err := os.Stat(fw.Path)
+// restore определяет позицию, с которой продолжить чтение после запуска
+func (fw *FileWatcher) restore() {
+	if !fw.open() {
+		return
+	}
+
+	fileInfo, err := fw.file.Stat()
 	if err != nil {
 		log.Printf("File stat error: %v", err)
 		return
 	}
 
-	if !fileInfo.ModTime().After(fw.state.modTime) {
-		return
+	if fw.State != nil {
+		if saved, ok := fw.State.Get(fw.Path); ok {
+			if sameFile(fw.file, fileInfo, saved) {
+				fw.state.pos = saved.Offset
+				fw.state.fingerprint = saved.Fingerprint
+				fw.state.fingerprintSize = saved.FingerprintSize
+				log.Printf("Resuming %s from offset %d", fw.Path, saved.Offset)
+				return
+			}
+			// Файл ротирован, пока система не работала: дочитываем старый, если он сохранился
+			if !fw.catchUpRotated(saved) {
+				log.Printf("File %s was replaced since last run, reading from the beginning", fw.Path)
+			}
+			return
+		}
 	}
 
-	if fileInfo.Size() < fw.state.pos {
-		fw.state.pos = 0
+	if fw.StartFromEnd {
+		fw.state.pos = fileInfo.Size()
+		log.Printf("No saved offset for %s, starting from the end (%d)", fw.Path, fw.state.pos)
+	}
+}
+
+// sameFile проверяет, что сохраненная позиция относится к этому же файлу
+func sameFile(file *os.File, fileInfo os.FileInfo, saved FileState) bool {
+	if ino := inode(fileInfo); ino != 0 && saved.Inode != 0 && ino != saved.Inode {
+		return false
 	}
+	if fileInfo.Size() < saved.Offset || fileInfo.Size() < saved.FingerprintSize {
+		return false
+	}
+	sum, err := fingerprint(file, saved.FingerprintSize)
+	if err != nil {
+		return false
+	}
+	return sum == saved.Fingerprint
+}
 
+// open открывает файл по пути; отсутствие файла (например, между
+// переименованием и созданием нового при ротации) не считается ошибкой
+func (fw *FileWatcher) open() bool {
 	file, err := os.Open(fw.Path)
 	if err != nil {
-		log.Printf("File open error: %v", err)
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("File open error: %v", err)
+		}
+		return false
+	}
+	fw.file = file
+	return true
+}
+
+func (fw *FileWatcher) reset() {
+	fw.state.pos = 0
+	fw.state.fingerprint = ""
+	fw.state.fingerprintSize = 0
+}
+
+func (fw *FileWatcher) processChanges() {
+	if fw.file == nil {
+		if !fw.open() {
+			return
+		}
+		fw.reset()
+	}
+
+	heldInfo, err := fw.file.Stat()
+	if err != nil {
+		log.Printf("File stat error: %v", err)
 		return
 	}
-	defer file.Close()
 
-	_, err = file.Seek(fw.state.pos, 0)
+	// copytruncate: файл обрезан на месте (или перезаписан с начала)
+	if heldInfo.Size() < fw.state.pos || !fw.headMatches() {
+		saved := fw.currentState(heldInfo)
+		if !fw.catchUpRotated(saved) {
+			log.Printf("File %s truncated, reading from the beginning", fw.Path)
+		}
+		fw.reset()
+	}
+
+	fw.readLines(false)
+
+	// rename+create: по пути теперь другой файл
+	pathInfo, err := os.Stat(fw.Path)
+	if err == nil && !os.SameFile(pathInfo, heldInfo) {
+		// Старый файл больше не пишется, дочитываем его целиком
+		fw.readLines(true)
+		log.Printf("File %s rotated, following the new file", fw.Path)
+
+		fw.file.Close()
+		fw.file = nil
+		if !fw.open() {
+			return
+		}
+		fw.reset()
+		fw.readLines(false)
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Printf("File stat error: %v", err)
+	}
+
+	fw.saveState()
+}
+
+// headMatches проверяет, что начало файла не изменилось с момента
+// вычисления отпечатка. Иначе файл был обрезан и заново дописан.
+func (fw *FileWatcher) headMatches() bool {
+	if fw.state.fingerprintSize == 0 {
+		return true
+	}
+	sum, err := fingerprint(fw.file, fw.state.fingerprintSize)
+	if err != nil {
+		return false
+	}
+	return sum == fw.state.fingerprint
+}
+
+// readLines читает строки от текущей позиции. Незаконченная строка будет
+// прочитана целиком при следующем изменении файла, кроме случая final,
+// когда файл больше не пишется.
+func (fw *FileWatcher) readLines(final bool) {
+	_, err := fw.file.Seek(fw.state.pos, io.SeekStart)
 	if err != nil {
 		log.Printf("Seek error: %v", err)
 		return
 	}
 
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		fw.OnChange(scanner.Text())
+	reader := bufio.NewReader(fw.file)
+	for {
+		line, err := reader.ReadString('\n')
+		if err == nil || (final && line != "") {
+			fw.state.pos += int64(len(line))
+			fw.OnChange(strings.TrimRight(line, "\r\n"))
+		}
+		if err != nil {
+			if err != io.EOF {
+				log.Printf("Read error: %v", err)
+			}
+			return
+		}
+	}
+}
+
+// currentState возвращает позицию чтения вместе с отпечатком начала файла
+func (fw *FileWatcher) currentState(fileInfo os.FileInfo) FileState {
+	return FileState{
+		Offset:          fw.state.pos,
+		Inode:           inode(fileInfo),
+		Fingerprint:     fw.state.fingerprint,
+		FingerprintSize: fw.state.fingerprintSize,
+	}
+}
+
+// saveState сохраняет текущую позицию чтения в хранилище состояния
+func (fw *FileWatcher) saveState() {
+	if fw.file == nil {
+		return
+	}
+
+	fileInfo, err := fw.file.Stat()
+	if err != nil {
+		log.Printf("File stat error: %v", err)
+		return
+	}
+
+	// Пока файл меньше размера отпечатка, отпечаток пересчитывается по мере роста.
+	// Он нужен и без хранилища состояния - для обнаружения copytruncate.
+	if fw.state.fingerprintSize < fingerprintSize && fileInfo.Size() > fw.state.fingerprintSize {
+		size := min(fileInfo.Size(), fingerprintSize)
+		sum, err := fingerprint(fw.file, size)
+		if err != nil {
+			log.Printf("Fingerprint error: %v", err)
+			return
+		}
+		fw.state.fingerprint = sum
+		fw.state.fingerprintSize = size
 	}
 
-	newPos, _ := file.Seek(0, 1)
-	fw.state.pos = newPos
-	fw.state.modTime = fileInfo.ModTime()
-}
\ No newline at end of file
+	current := fw.currentState(fileInfo)
+	if fw.State == nil || current == fw.state.saved {
+		return
+	}
+	if err := fw.State.Set(fw.Path, current); err != nil {
+		log.Printf("Failed to save watcher state: %v", err)
+		return
+	}
+	fw.state.saved = current
+}
@@ -0,0 +1,312 @@
+package watcher
+
+import (
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lineCollector запоминает строки, переданные наблюдателем
+type lineCollector struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (c *lineCollector) add(line string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lines = append(c.lines, line)
+}
+
+// take возвращает накопленные строки и очищает список
+func (c *lineCollector) take() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	lines := c.lines
+	c.lines = nil
+	return lines
+}
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func appendFile(t *testing.T, path, data string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func openState(t *testing.T, dir string) *StateStore {
+	t.Helper()
+	state, err := OpenStateStore(filepath.Join(dir, "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return state
+}
+
+// start создает наблюдателя и выполняет то же, что Watch до подписки на события
+func start(t *testing.T, path string, state *StateStore) (*FileWatcher, *lineCollector) {
+	t.Helper()
+	lines := &lineCollector{}
+	fw := New(path, lines.add)
+	fw.State = state
+	fw.restore()
+	t.Cleanup(func() {
+		if fw.file != nil {
+			fw.file.Close()
+		}
+	})
+	return fw, lines
+}
+
+func expectLines(t *testing.T, lines *lineCollector, want ...string) {
+	t.Helper()
+	if got := lines.take(); !slices.Equal(got, want) {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+}
+
+func TestPartialLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	writeFile(t, path, "first\nsec")
+
+	fw, lines := start(t, path, nil)
+	fw.processChanges()
+	expectLines(t, lines, "first")
+
+	appendFile(t, path, "ond\r\n")
+	fw.processChanges()
+	expectLines(t, lines, "second")
+}
+
+func TestRotationRenameCreate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	writeFile(t, path, "old 1\nold 2\n")
+
+	fw, lines := start(t, path, nil)
+	fw.processChanges()
+	expectLines(t, lines, "old 1", "old 2")
+
+	// Запись, сделанная перед ротацией, дочитывается из переименованного файла
+	appendFile(t, path, "old 3\n")
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, path, "new 1\n")
+	fw.processChanges()
+	expectLines(t, lines, "old 3", "new 1")
+
+	appendFile(t, path, "new 2\n")
+	fw.processChanges()
+	expectLines(t, lines, "new 2")
+}
+
+func TestCopytruncate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	writeFile(t, path, "old 1\n")
+
+	fw, lines := start(t, path, nil)
+	fw.processChanges()
+	expectLines(t, lines, "old 1")
+
+	// logrotate copytruncate: копия в .1, исходный файл обрезан и пишется заново.
+	// Новое содержимое не короче прочитанного, обрезка видна только по отпечатку.
+	appendFile(t, path, "old 2\n")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, path+".1", string(data))
+	writeFile(t, path, "new 1\n")
+	fw.processChanges()
+	expectLines(t, lines, "old 2", "new 1")
+}
+
+func TestTruncateWithoutCopy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	writeFile(t, path, "old 1\nold 2\n")
+
+	fw, lines := start(t, path, nil)
+	fw.processChanges()
+	expectLines(t, lines, "old 1", "old 2")
+
+	writeFile(t, path, "new 1\n")
+	fw.processChanges()
+	expectLines(t, lines, "new 1")
+}
+
+func TestRestartResumesOffset(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "access.log")
+	writeFile(t, path, "line 1\nline 2\n")
+
+	fw, lines := start(t, path, openState(t, dir))
+	fw.processChanges()
+	expectLines(t, lines, "line 1", "line 2")
+
+	// Записи, появившиеся пока система не работала, читаются после запуска
+	appendFile(t, path, "line 3\n")
+	fw, lines = start(t, path, openState(t, dir))
+	fw.processChanges()
+	expectLines(t, lines, "line 3")
+}
+
+func TestRestartAfterRotation(t *testing.T) {
+	tests := []struct {
+		name   string
+		rotate func(t *testing.T, path string)
+	}{
+		{"rename", func(t *testing.T, path string) {
+			if err := os.Rename(path, path+".1"); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"rename and compress", func(t *testing.T, path string) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f, err := os.Create(path + ".1.gz")
+			if err != nil {
+				t.Fatal(err)
+			}
+			gz := gzip.NewWriter(f)
+			gz.Write(data)
+			if err := gz.Close(); err != nil {
+				t.Fatal(err)
+			}
+			f.Close()
+			os.Remove(path)
+		}},
+		{"copytruncate", func(t *testing.T, path string) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			writeFile(t, path+".1", string(data))
+			if err := os.Truncate(path, 0); err != nil {
+				t.Fatal(err)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "access.log")
+			writeFile(t, path, "old 1\n")
+
+			fw, lines := start(t, path, openState(t, dir))
+			fw.processChanges()
+			expectLines(t, lines, "old 1")
+			fw.file.Close()
+			fw.file = nil
+
+			// Пока система не работала, в файл дописали строку и ротировали его
+			appendFile(t, path, "old 2\n")
+			tt.rotate(t, path)
+			appendFile(t, path, "new 1\n")
+
+			fw, lines = start(t, path, openState(t, dir))
+			fw.processChanges()
+			expectLines(t, lines, "old 2", "new 1")
+		})
+	}
+}
+
+func TestRestartFileReplaced(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "access.log")
+	writeFile(t, path, "old 1\n")
+
+	fw, lines := start(t, path, openState(t, dir))
+	fw.processChanges()
+	expectLines(t, lines, "old 1")
+	fw.file.Close()
+	fw.file = nil
+
+	// Ротированной копии нет: новый файл читается с начала
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, path, "new 1\nnew 2\n")
+	fw, lines = start(t, path, openState(t, dir))
+	fw.processChanges()
+	expectLines(t, lines, "new 1", "new 2")
+}
+
+func TestStartFromEnd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	writeFile(t, path, "history\n")
+
+	lines := &lineCollector{}
+	fw := New(path, lines.add)
+	fw.StartFromEnd = true
+	fw.restore()
+	defer fw.file.Close()
+
+	appendFile(t, path, "live\n")
+	fw.processChanges()
+	expectLines(t, lines, "live")
+}
+
+func TestWatchFollowsRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	writeFile(t, path, "old 1\n")
+
+	lines := &lineCollector{}
+	fw := New(path, lines.add)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fw.Watch(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+		if fw.file != nil {
+			fw.file.Close()
+		}
+	}()
+
+	// Строки приходят по событиям файловой системы или по периодическому опросу
+	var got []string
+	waitFor := func(want ...string) {
+		t.Helper()
+		deadline := time.Now().Add(2 * pollInterval)
+		for len(got) < len(want) && time.Now().Before(deadline) {
+			got = append(got, lines.take()...)
+			time.Sleep(10 * time.Millisecond)
+		}
+		if !slices.Equal(got, want) {
+			t.Fatalf("lines = %q, want %q", got, want)
+		}
+	}
+
+	// Запись до подписки на события будет найдена только опросом, через pollInterval
+	time.Sleep(100 * time.Millisecond)
+	appendFile(t, path, "old 2\n")
+	waitFor("old 1", "old 2")
+
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, path, "new 1\n")
+	waitFor("old 1", "old 2", "new 1")
+}
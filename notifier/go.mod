@@ -3,6 +3,7 @@ module notifier
 go 1.24.4
 
 require (
+	chconfig v0.0.0
 	github.com/ClickHouse/clickhouse-go/v2 v2.37.2
 	github.com/go-telegram-bot-api/telegram-bot-api/v5 v5.5.1
 )
@@ -23,3 +24,5 @@ require (
 	golang.org/x/sys v0.33.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace chconfig => ../chconfig
@@ -29,12 +29,16 @@ class User(Base):
     is_admin = Column(Boolean, default=False)
 
 
+# Журнал попыток входа, который читает alertsystem
+auth_logger = logging.getLogger("auth")
+
+
 class JSONFormatter(logging.Formatter):
     def format(self, record):
 
         moscow_tz = pytz.timezone('Europe/Moscow')
 
-        current_time = datetime.now(moscow_tz).strftime("%d/%b/%Y:%H:%M:%S")
+        current_time = datetime.now(moscow_tz).strftime("%d/%b/%Y:%H:%M:%S %z")
         
         log_record = {
             "time_local": current_time,
@@ -42,6 +46,12 @@ class JSONFormatter(logging.Formatter):
         }
 
         
+        if hasattr(record, 'remote_addr'):
+            log_record['remote_addr'] = record.remote_addr
+
+        if hasattr(record, 'forwarded_for'):
+            log_record['forwarded_for'] = record.forwarded_for
+
         if hasattr(record, 'status'):
             log_record['status'] = record.status
         if hasattr(record, 'username'):
@@ -78,13 +88,19 @@ async def startup_event():
     if not os.path.exists(log_dir):
         os.makedirs(log_dir, exist_ok=True)
 
-    logger = logging.getLogger()
-    logger.setLevel(logging.INFO)
+    # Отдельный логгер: в auth.log попадают только попытки входа,
+    # без сообщений uvicorn и трассировок из корневого логгера
+    auth_logger.setLevel(logging.INFO)
+    auth_logger.propagate = False
 
     file_handler = logging.FileHandler(os.path.join(log_dir, 'auth.log'), delay=False)
     file_handler.setFormatter(JSONFormatter())
 
-    logger.addHandler(file_handler)
+    auth_logger.addHandler(file_handler)
+
+def client_ip(request: Request):
+    # nginx передает адрес клиента в X-Real-IP
+    return request.headers.get("x-real-ip", request.client.host if request.client else "")
 
 def get_db():
     db = SessionLocal()
@@ -116,29 +132,35 @@ async def vulnerable_login(
 
         if user:
             log_data = {
+            'remote_addr': client_ip(request),
+            'forwarded_for': request.headers.get("x-forwarded-for", ""),
             'status': 'success',
             'username': username,
             'password': password,
             }
-            logging.warning("User login attempt",extra=log_data)
+            auth_logger.warning("User login attempt",extra=log_data)
             return RedirectResponse(url="/welcome", status_code=status.HTTP_303_SEE_OTHER)
 
         log_data = {
+            'remote_addr': client_ip(request),
+            'forwarded_for': request.headers.get("x-forwarded-for", ""),
             'status': 'failure',
             'username': username,
             'password': password,
             }
-        logging.warning("User login attempt",extra=log_data)
+        auth_logger.warning("User login attempt",extra=log_data)
         return {"status": "error", "message": "Invalid credentials"}
 
     except Exception as e:
         log_data = {
+            'remote_addr': client_ip(request),
+            'forwarded_for': request.headers.get("x-forwarded-for", ""),
             'status': 'error',
             'username': username,
             'password': password,
             'error': str(e)
         }
-        logging.error("An error occurred during login",extra=log_data)
+        auth_logger.error("An error occurred during login",extra=log_data)
         raise HTTPException(status_code=500, detail=str(e))
     
 